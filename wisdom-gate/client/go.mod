@@ -1,3 +1,12 @@
 module client
 
 go 1.24.0
+
+require wisdom-gate v0.0.0
+
+require (
+	golang.org/x/crypto v0.42.0 // indirect
+	golang.org/x/sys v0.36.0 // indirect
+)
+
+replace wisdom-gate => ../wisdom-gate
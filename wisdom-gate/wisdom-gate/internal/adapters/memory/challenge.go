@@ -0,0 +1,204 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"wisdom-gate/internal/adapters/redis"
+)
+
+var ErrStoreFull = errors.New("challenge store is full")
+
+var _ redis.ClientInterface = (*ChallengeStore)(nil)
+
+type challengeEntry struct {
+	challenge string
+	expiresAt time.Time
+}
+
+// ChallengeStore — хранилище challenge в памяти процесса для инсталляций без Redis.
+// Записи живут до истечения TTL, фоновая горутина периодически удаляет
+// просроченные, а при достижении лимита новые записи отклоняются, а не вытесняют
+// ещё действующие: иначе поток REQ мог бы стереть challenge честных клиентов.
+// Просроченные записи снимаются с начала очередей по сроку, поэтому вставка
+// в заполненное хранилище не обходит все записи под общей блокировкой.
+type ChallengeStore struct {
+	mu              sync.Mutex
+	challenges      map[string]challengeEntry
+	challengeExpiry *expiryQueue
+	spent           map[string]time.Time
+	spentExpiry     *expiryQueue
+	maxEntries      int
+	now             func() time.Time
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func NewChallengeStore(maxEntries int, sweepInterval time.Duration) (*ChallengeStore, error) {
+	if maxEntries <= 0 {
+		return nil, fmt.Errorf("max entries must be positive, got %d", maxEntries)
+	}
+	if sweepInterval <= 0 {
+		return nil, fmt.Errorf("sweep interval must be positive, got %s", sweepInterval)
+	}
+
+	s := &ChallengeStore{
+		challenges:      make(map[string]challengeEntry),
+		challengeExpiry: newExpiryQueue(),
+		spent:           make(map[string]time.Time),
+		spentExpiry:     newExpiryQueue(),
+		maxEntries:      maxEntries,
+		now:             time.Now,
+		stop:            make(chan struct{}),
+		done:            make(chan struct{}),
+	}
+
+	go s.janitor(sweepInterval)
+
+	return s, nil
+}
+
+func (s *ChallengeStore) StoreChallenge(ctx context.Context, token string, challenge string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if _, exists := s.challenges[token]; !exists && len(s.challenges) >= s.maxEntries {
+		s.sweep(now)
+		if len(s.challenges) >= s.maxEntries {
+			return ErrStoreFull
+		}
+	}
+
+	expiresAt := now.Add(ttl)
+	s.challenges[token] = challengeEntry{challenge: challenge, expiresAt: expiresAt}
+	s.challengeExpiry.set(token, expiresAt)
+
+	return nil
+}
+
+func (s *ChallengeStore) GetChallenge(ctx context.Context, token string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, exists := s.challenges[token]
+	if !exists || !s.now().Before(entry.expiresAt) {
+		return "", redis.ErrChallengeNotFound
+	}
+
+	return entry.challenge, nil
+}
+
+func (s *ChallengeStore) MarkChallengeSpent(ctx context.Context, token string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if s.isSpent(token, now) {
+		return false, nil
+	}
+
+	if err := s.markSpent(token, now.Add(ttl)); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (s *ChallengeStore) ConsumeChallenge(ctx context.Context, token string, spentTTL time.Duration) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if s.isSpent(token, now) {
+		return "", false, nil
+	}
+
+	entry, exists := s.challenges[token]
+	if !exists || !now.Before(entry.expiresAt) {
+		return "", false, redis.ErrChallengeNotFound
+	}
+
+	if err := s.markSpent(token, now.Add(spentTTL)); err != nil {
+		return "", false, err
+	}
+	s.deleteChallenge(token)
+
+	return entry.challenge, true, nil
+}
+
+func (s *ChallengeStore) DeleteChallenge(ctx context.Context, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deleteChallenge(token)
+
+	return nil
+}
+
+// Close останавливает фоновую очистку, повторный вызов безопасен
+func (s *ChallengeStore) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.stop)
+		<-s.done
+	})
+
+	return nil
+}
+
+func (s *ChallengeStore) isSpent(token string, now time.Time) bool {
+	expiresAt, exists := s.spent[token]
+	return exists && now.Before(expiresAt)
+}
+
+// markSpent вызывается под блокировкой. Переполненное множество потраченных
+// токенов приводит к ошибке: забыть токен значило бы разрешить повтор
+func (s *ChallengeStore) markSpent(token string, expiresAt time.Time) error {
+	if _, exists := s.spent[token]; !exists && len(s.spent) >= s.maxEntries {
+		s.sweep(s.now())
+		if len(s.spent) >= s.maxEntries {
+			return ErrStoreFull
+		}
+	}
+
+	s.spent[token] = expiresAt
+	s.spentExpiry.set(token, expiresAt)
+
+	return nil
+}
+
+func (s *ChallengeStore) deleteChallenge(token string) {
+	delete(s.challenges, token)
+	s.challengeExpiry.remove(token)
+}
+
+// sweep удаляет просроченные записи, вызывается под блокировкой.
+// Каждая запись снимается с очереди один раз, поэтому стоимость пропорциональна
+// числу истёкших записей, а не размеру хранилища
+func (s *ChallengeStore) sweep(now time.Time) {
+	s.challengeExpiry.popExpired(now, func(token string) { delete(s.challenges, token) })
+	s.spentExpiry.popExpired(now, func(token string) { delete(s.spent, token) })
+}
+
+func (s *ChallengeStore) janitor(interval time.Duration) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			s.sweep(s.now())
+			s.mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"wisdom-gate/internal/adapters/redis"
+)
+
+func newTestChallengeStore(t *testing.T, maxEntries int) (*ChallengeStore, *time.Time) {
+	t.Helper()
+
+	store, err := NewChallengeStore(maxEntries, time.Hour)
+	if err != nil {
+		t.Fatalf("NewChallengeStore() error = %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	return store, &now
+}
+
+func TestChallengeStore_TTL(t *testing.T) {
+	store, now := newTestChallengeStore(t, 10)
+	ctx := context.Background()
+
+	if err := store.StoreChallenge(ctx, "token", "challenge", time.Minute); err != nil {
+		t.Fatalf("StoreChallenge() error = %v", err)
+	}
+
+	got, err := store.GetChallenge(ctx, "token")
+	if err != nil || got != "challenge" {
+		t.Fatalf("GetChallenge() = %v, %v, want challenge", got, err)
+	}
+
+	*now = now.Add(time.Minute)
+	if _, err := store.GetChallenge(ctx, "token"); !errors.Is(err, redis.ErrChallengeNotFound) {
+		t.Errorf("GetChallenge() after ttl error = %v, want %v", err, redis.ErrChallengeNotFound)
+	}
+	if _, _, err := store.ConsumeChallenge(ctx, "token", time.Minute); !errors.Is(err, redis.ErrChallengeNotFound) {
+		t.Errorf("ConsumeChallenge() after ttl error = %v, want %v", err, redis.ErrChallengeNotFound)
+	}
+}
+
+func TestChallengeStore_Consume(t *testing.T) {
+	store, now := newTestChallengeStore(t, 10)
+	ctx := context.Background()
+
+	if err := store.StoreChallenge(ctx, "token", "challenge", time.Minute); err != nil {
+		t.Fatalf("StoreChallenge() error = %v", err)
+	}
+
+	got, consumed, err := store.ConsumeChallenge(ctx, "token", 2*time.Minute)
+	if err != nil || !consumed || got != "challenge" {
+		t.Fatalf("ConsumeChallenge() = %v, %v, %v, want challenge, true, nil", got, consumed, err)
+	}
+
+	_, consumed, err = store.ConsumeChallenge(ctx, "token", 2*time.Minute)
+	if err != nil || consumed {
+		t.Errorf("ConsumeChallenge() replay = %v, %v, want false, nil", consumed, err)
+	}
+
+	// Метка потраченного живёт spentTTL, после чего токен забывается
+	*now = now.Add(2 * time.Minute)
+	ok, err := store.MarkChallengeSpent(ctx, "token", time.Minute)
+	if err != nil || !ok {
+		t.Errorf("MarkChallengeSpent() after spent ttl = %v, %v, want true, nil", ok, err)
+	}
+}
+
+func TestChallengeStore_Bounded(t *testing.T) {
+	store, now := newTestChallengeStore(t, 2)
+	ctx := context.Background()
+
+	for _, token := range []string{"a", "b"} {
+		if err := store.StoreChallenge(ctx, token, "challenge", time.Minute); err != nil {
+			t.Fatalf("StoreChallenge() error = %v", err)
+		}
+	}
+
+	if err := store.StoreChallenge(ctx, "c", "challenge", time.Minute); !errors.Is(err, ErrStoreFull) {
+		t.Errorf("StoreChallenge() over limit error = %v, want %v", err, ErrStoreFull)
+	}
+
+	// Действующие challenge не вытесняются
+	if _, err := store.GetChallenge(ctx, "a"); err != nil {
+		t.Errorf("GetChallenge() error = %v", err)
+	}
+
+	// После истечения TTL место освобождается
+	*now = now.Add(time.Minute)
+	if err := store.StoreChallenge(ctx, "c", "challenge", time.Minute); err != nil {
+		t.Errorf("StoreChallenge() after expiry error = %v", err)
+	}
+	if len(store.challenges) != 1 {
+		t.Errorf("len(challenges) = %d, want 1", len(store.challenges))
+	}
+}
+
+func TestChallengeStore_Janitor(t *testing.T) {
+	store, err := NewChallengeStore(10, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewChallengeStore() error = %v", err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+
+	if err := store.StoreChallenge(ctx, "token", "challenge", time.Millisecond); err != nil {
+		t.Fatalf("StoreChallenge() error = %v", err)
+	}
+	if _, err := store.MarkChallengeSpent(ctx, "spent", time.Millisecond); err != nil {
+		t.Fatalf("MarkChallengeSpent() error = %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		store.mu.Lock()
+		empty := len(store.challenges) == 0 && len(store.spent) == 0
+		store.mu.Unlock()
+		if empty {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	t.Error("janitor did not evict expired entries")
+}
+
+func TestChallengeStore_ConcurrentConsume(t *testing.T) {
+	store, err := NewChallengeStore(1000, time.Hour)
+	if err != nil {
+		t.Fatalf("NewChallengeStore() error = %v", err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+
+	for i := range 100 {
+		if err := store.StoreChallenge(ctx, fmt.Sprint(i), "challenge", time.Minute); err != nil {
+			t.Fatalf("StoreChallenge() error = %v", err)
+		}
+	}
+
+	// Каждый challenge должен быть погашен ровно один раз
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		consumed = make(map[string]int)
+	)
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 100 {
+				token := fmt.Sprint(i)
+				if _, ok, err := store.ConsumeChallenge(ctx, token, time.Minute); err == nil && ok {
+					mu.Lock()
+					consumed[token]++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(consumed) != 100 {
+		t.Errorf("consumed %d challenges, want 100", len(consumed))
+	}
+	for token, n := range consumed {
+		if n != 1 {
+			t.Errorf("challenge %s consumed %d times, want 1", token, n)
+		}
+	}
+}
+
+func TestChallengeStore_CloseIdempotent(t *testing.T) {
+	store, err := NewChallengeStore(10, time.Hour)
+	if err != nil {
+		t.Fatalf("NewChallengeStore() error = %v", err)
+	}
+
+	if err := store.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Errorf("second Close() error = %v", err)
+	}
+}
+
+func TestNewChallengeStore_InvalidSweepInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		if _, err := NewChallengeStore(10, interval); err == nil {
+			t.Errorf("NewChallengeStore(%s) error = nil, want error", interval)
+		}
+	}
+}
+
+func TestNewChallengeStore_InvalidMaxEntries(t *testing.T) {
+	for _, maxEntries := range []int{0, -1} {
+		if _, err := NewChallengeStore(maxEntries, time.Hour); err == nil {
+			t.Errorf("NewChallengeStore(%d) error = nil, want error", maxEntries)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package memory
+
+import (
+	"container/heap"
+	"time"
+)
+
+// expiryQueue упорядочивает ключи по сроку истечения: просроченные снимаются
+// с начала очереди за O(log n) на ключ, без обхода всех записей под блокировкой
+type expiryQueue struct {
+	heap  expiryHeap
+	items map[string]*expiryItem
+}
+
+type expiryItem struct {
+	key       string
+	expiresAt time.Time
+	index     int
+}
+
+func newExpiryQueue() *expiryQueue {
+	return &expiryQueue{items: make(map[string]*expiryItem)}
+}
+
+// set добавляет ключ или переносит срок уже известного
+func (q *expiryQueue) set(key string, expiresAt time.Time) {
+	if item, exists := q.items[key]; exists {
+		item.expiresAt = expiresAt
+		heap.Fix(&q.heap, item.index)
+		return
+	}
+
+	item := &expiryItem{key: key, expiresAt: expiresAt}
+	q.items[key] = item
+	heap.Push(&q.heap, item)
+}
+
+func (q *expiryQueue) remove(key string) {
+	item, exists := q.items[key]
+	if !exists {
+		return
+	}
+
+	heap.Remove(&q.heap, item.index)
+	delete(q.items, key)
+}
+
+// popExpired снимает ключи, срок которых наступил к now, и передаёт их в evict
+func (q *expiryQueue) popExpired(now time.Time, evict func(key string)) {
+	for len(q.heap) > 0 && !now.Before(q.heap[0].expiresAt) {
+		item := heap.Pop(&q.heap).(*expiryItem)
+		delete(q.items, item.key)
+		evict(item.key)
+	}
+}
+
+type expiryHeap []*expiryItem
+
+func (h expiryHeap) Len() int { return len(h) }
+
+func (h expiryHeap) Less(i, j int) bool { return h[i].expiresAt.Before(h[j].expiresAt) }
+
+func (h expiryHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *expiryHeap) Push(x any) {
+	item := x.(*expiryItem)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *expiryHeap) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return item
+}
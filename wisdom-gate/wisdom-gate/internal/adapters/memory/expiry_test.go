@@ -0,0 +1,31 @@
+package memory
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestExpiryQueue_PopExpired(t *testing.T) {
+	now := time.Now()
+	q := newExpiryQueue()
+
+	q.set("c", now.Add(3*time.Second))
+	q.set("a", now.Add(time.Second))
+	q.set("b", now.Add(2*time.Second))
+	q.set("d", now.Add(4*time.Second))
+
+	// Перенос срока и удаление сохраняют порядок очереди
+	q.set("a", now.Add(5*time.Second))
+	q.remove("c")
+
+	var got []string
+	q.popExpired(now.Add(4*time.Second), func(key string) { got = append(got, key) })
+
+	if want := []string{"b", "d"}; !slices.Equal(got, want) {
+		t.Errorf("popExpired() = %v, want %v", got, want)
+	}
+	if len(q.items) != 1 || len(q.heap) != 1 {
+		t.Errorf("queue size = %d/%d, want 1", len(q.items), len(q.heap))
+	}
+}
@@ -0,0 +1,91 @@
+package memory
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// negligibleScore — счёт, ниже которого запись можно забыть
+const negligibleScore = 0.01
+
+type reputationEntry struct {
+	score     float64
+	updatedAt time.Time
+}
+
+// ReputationStore — хранилище репутации в памяти процесса с ограничением числа клиентов.
+// Клиенты упорядочены по моменту, когда их счёт затухнет до пренебрежимого:
+// при заполнении забываются только такие записи, без обхода всего хранилища
+type ReputationStore struct {
+	mu         sync.Mutex
+	entries    map[string]reputationEntry
+	forgetAt   *expiryQueue
+	maxEntries int
+	now        func() time.Time
+}
+
+func NewReputationStore(maxEntries int) *ReputationStore {
+	return &ReputationStore{
+		entries:    make(map[string]reputationEntry),
+		forgetAt:   newExpiryQueue(),
+		maxEntries: maxEntries,
+		now:        time.Now,
+	}
+}
+
+func (s *ReputationStore) Add(ctx context.Context, client string, weight float64, halfLife time.Duration) (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	entry, exists := s.entries[client]
+	if !exists && len(s.entries) >= s.maxEntries {
+		s.forgetAt.popExpired(now, func(client string) { delete(s.entries, client) })
+		if len(s.entries) >= s.maxEntries {
+			// Хранилище заполнено значимыми записями: нового клиента не отслеживаем
+			return weight, nil
+		}
+	}
+
+	score := decay(entry.score, now.Sub(entry.updatedAt), halfLife) + weight
+	s.entries[client] = reputationEntry{score: score, updatedAt: now}
+	if halfLife > 0 {
+		s.forgetAt.set(client, negligibleAt(score, now, halfLife))
+	} else {
+		// Без затухания счёт не становится пренебрежимым
+		s.forgetAt.remove(client)
+	}
+
+	return score, nil
+}
+
+func (s *ReputationStore) Score(ctx context.Context, client string, halfLife time.Duration) (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, exists := s.entries[client]
+	if !exists {
+		return 0, nil
+	}
+
+	return decay(entry.score, s.now().Sub(entry.updatedAt), halfLife), nil
+}
+
+// negligibleAt — момент, когда счёт score, набранный к now, затухнет до negligibleScore
+func negligibleAt(score float64, now time.Time, halfLife time.Duration) time.Time {
+	if score < negligibleScore {
+		return now
+	}
+
+	return now.Add(time.Duration(float64(halfLife) * math.Log2(score/negligibleScore)))
+}
+
+func decay(score float64, elapsed, halfLife time.Duration) float64 {
+	if score == 0 || elapsed <= 0 || halfLife <= 0 {
+		return score
+	}
+
+	return score * math.Exp2(-float64(elapsed)/float64(halfLife))
+}
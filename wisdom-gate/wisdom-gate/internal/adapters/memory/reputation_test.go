@@ -0,0 +1,91 @@
+package memory
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestReputationStore_Decay(t *testing.T) {
+	store := NewReputationStore(10)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if _, err := store.Add(ctx, "10.0.0.1", 8, time.Minute); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	score, err := store.Score(ctx, "10.0.0.1", time.Minute)
+	if err != nil {
+		t.Fatalf("Score() error = %v", err)
+	}
+	if math.Abs(score-2) > 1e-9 {
+		t.Errorf("Score() after two half-lives = %v, want 2", score)
+	}
+
+	score, err = store.Add(ctx, "10.0.0.1", 1, time.Minute)
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if math.Abs(score-3) > 1e-9 {
+		t.Errorf("Add() = %v, want 3", score)
+	}
+}
+
+func TestReputationStore_Bounded(t *testing.T) {
+	store := NewReputationStore(2)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for _, client := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+		if _, err := store.Add(ctx, client, 1, time.Minute); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	if len(store.entries) != 2 {
+		t.Fatalf("entries = %d, want 2", len(store.entries))
+	}
+
+	// Старые записи затухли, место освобождается для нового клиента
+	now = now.Add(time.Hour)
+	if _, err := store.Add(ctx, "10.0.0.3", 1, time.Minute); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	if score, _ := store.Score(ctx, "10.0.0.3", time.Minute); score != 1 {
+		t.Errorf("Score() = %v, want 1", score)
+	}
+	if len(store.entries) != 1 {
+		t.Errorf("entries = %d, want 1", len(store.entries))
+	}
+}
+
+func TestReputationStore_ForgetsNegligibleFirst(t *testing.T) {
+	store := NewReputationStore(2)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_, _ = store.Add(ctx, "10.0.0.1", 8, time.Minute)
+	_, _ = store.Add(ctx, "10.0.0.2", 1, time.Minute)
+
+	// Через семь периодов полураспада 1 затухает ниже порога, а 8 — ещё нет
+	now = now.Add(7 * time.Minute)
+	if _, err := store.Add(ctx, "10.0.0.3", 1, time.Minute); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	if _, ok := store.entries["10.0.0.2"]; ok {
+		t.Error("negligible client 10.0.0.2 is still tracked")
+	}
+	for _, client := range []string{"10.0.0.1", "10.0.0.3"} {
+		if _, ok := store.entries[client]; !ok {
+			t.Errorf("client %s is not tracked", client)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package redis
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -9,41 +10,102 @@ import (
 )
 
 type Client struct {
-	rdb *redis.Client
+	rdb redis.UniversalClient
 }
 
-func NewClient(addr string) (ClientInterface, error) {
-	rdb := redis.NewClient(&redis.Options{
-		Addr: addr,
-	})
+func NewClient(opts Options) (*Client, error) {
+	rdb, err := newUniversalClient(opts)
+	if err != nil {
+		return nil, err
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	if err := rdb.Ping(ctx).Err(); err != nil {
+		_ = rdb.Close()
 		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
 	return &Client{rdb: rdb}, nil
 }
 
+// Ключи challenge и метки потраченного содержат общий hash tag, чтобы
+// в Cluster попадать в один слот и обрабатываться одним Lua-скриптом
+func challengeKey(token string) string {
+	return fmt.Sprintf("challenge:{%s}", token)
+}
+
+func spentKey(token string) string {
+	return fmt.Sprintf("challenge:spent:{%s}", token)
+}
+
 func (c *Client) StoreChallenge(ctx context.Context, token string, challenge string, ttl time.Duration) error {
-	key := fmt.Sprintf("challenge:%s", token)
+	key := challengeKey(token)
 	return c.rdb.Set(ctx, key, challenge, ttl).Err()
 }
 
 func (c *Client) GetChallenge(ctx context.Context, token string) (string, error) {
-	key := fmt.Sprintf("challenge:%s", token)
-	return c.rdb.Get(ctx, key).Result()
+	key := challengeKey(token)
+	challenge, err := c.rdb.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrChallengeNotFound
+	}
+
+	return challenge, err
 }
 
 func (c *Client) MarkChallengeSpent(ctx context.Context, token string, ttl time.Duration) (bool, error) {
-	key := fmt.Sprintf("challenge:spent:%s", token)
+	key := spentKey(token)
 	return c.rdb.SetNX(ctx, key, "1", ttl).Result()
 }
 
+// consumeChallengeScript: KEYS[1] — challenge, KEYS[2] — метка потраченного.
+// Возвращает {0} если challenge нет, {1} если уже потрачен, {2, challenge} при успехе.
+var consumeChallengeScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[2]) == 1 then
+	return {1}
+end
+local challenge = redis.call('GET', KEYS[1])
+if not challenge then
+	return {0}
+end
+redis.call('SET', KEYS[2], '1', 'PX', ARGV[1])
+redis.call('DEL', KEYS[1])
+return {2, challenge}
+`)
+
+const (
+	consumeNotFound int64 = iota
+	consumeSpent
+	consumeOK
+)
+
+func (c *Client) ConsumeChallenge(ctx context.Context, token string, spentTTL time.Duration) (string, bool, error) {
+	keys := []string{
+		challengeKey(token),
+		spentKey(token),
+	}
+
+	res, err := consumeChallengeScript.Run(ctx, c.rdb, keys, spentTTL.Milliseconds()).Slice()
+	if err != nil {
+		return "", false, err
+	}
+
+	code, _ := res[0].(int64)
+	switch code {
+	case consumeSpent:
+		return "", false, nil
+	case consumeOK:
+		challenge, _ := res[1].(string)
+		return challenge, true, nil
+	default:
+		return "", false, ErrChallengeNotFound
+	}
+}
+
 func (c *Client) DeleteChallenge(ctx context.Context, token string) error {
-	key := fmt.Sprintf("challenge:%s", token)
+	key := challengeKey(token)
 	return c.rdb.Del(ctx, key).Err()
 }
 
@@ -72,7 +134,7 @@ func (m *MockRedisClient) StoreChallenge(ctx context.Context, token string, chal
 func (m *MockRedisClient) GetChallenge(ctx context.Context, token string) (string, error) {
 	challenge, exists := m.challenges[token]
 	if !exists {
-		return "", context.DeadlineExceeded
+		return "", ErrChallengeNotFound
 	}
 	return challenge, nil
 }
@@ -85,6 +147,22 @@ func (m *MockRedisClient) MarkChallengeSpent(ctx context.Context, token string,
 	return true, nil
 }
 
+func (m *MockRedisClient) ConsumeChallenge(ctx context.Context, token string, spentTTL time.Duration) (string, bool, error) {
+	if m.spent[token] {
+		return "", false, nil
+	}
+
+	challenge, exists := m.challenges[token]
+	if !exists {
+		return "", false, ErrChallengeNotFound
+	}
+
+	m.spent[token] = true
+	delete(m.challenges, token)
+
+	return challenge, true, nil
+}
+
 func (m *MockRedisClient) DeleteChallenge(ctx context.Context, token string) error {
 	delete(m.challenges, token)
 	return nil
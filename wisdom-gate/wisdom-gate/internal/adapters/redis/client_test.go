@@ -2,6 +2,7 @@ package redis
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 )
@@ -54,8 +55,8 @@ func TestMockRedisClient_GetChallenge_NotFound(t *testing.T) {
 	ctx := context.Background()
 
 	_, err := client.GetChallenge(ctx, "non-existent-token")
-	if err == nil {
-		t.Error("GetChallenge() error = nil, want error")
+	if !errors.Is(err, ErrChallengeNotFound) {
+		t.Errorf("GetChallenge() error = %v, want %v", err, ErrChallengeNotFound)
 	}
 }
 
@@ -78,3 +79,55 @@ func TestMockRedisClient_DeleteChallenge(t *testing.T) {
 		t.Error("GetChallenge() error = nil, want error")
 	}
 }
+
+func TestMockRedisClient_ConsumeChallenge(t *testing.T) {
+	client := NewMockRedisClient()
+	ctx := context.Background()
+
+	_, _, err := client.ConsumeChallenge(ctx, "test-token", time.Minute)
+	if !errors.Is(err, ErrChallengeNotFound) {
+		t.Errorf("ConsumeChallenge() error = %v, want %v", err, ErrChallengeNotFound)
+	}
+
+	err = client.StoreChallenge(ctx, "test-token", "test-challenge", time.Minute)
+	if err != nil {
+		t.Errorf("StoreChallenge() error = %v", err)
+	}
+
+	// Первый раз возвращает challenge и помечает его потраченным
+	challenge, consumed, err := client.ConsumeChallenge(ctx, "test-token", time.Minute)
+	if err != nil {
+		t.Errorf("ConsumeChallenge() error = %v", err)
+	}
+	if !consumed || challenge != "test-challenge" {
+		t.Errorf("ConsumeChallenge() = %v, %v, want %v, true", challenge, consumed, "test-challenge")
+	}
+
+	// Challenge удалён при первом погашении
+	if _, err := client.GetChallenge(ctx, "test-token"); !errors.Is(err, ErrChallengeNotFound) {
+		t.Errorf("GetChallenge() after consume error = %v, want %v", err, ErrChallengeNotFound)
+	}
+
+	// Повторно — уже потрачен, даже если challenge сохранят заново
+	err = client.StoreChallenge(ctx, "test-token", "test-challenge", time.Minute)
+	if err != nil {
+		t.Errorf("StoreChallenge() error = %v", err)
+	}
+
+	_, consumed, err = client.ConsumeChallenge(ctx, "test-token", time.Minute)
+	if err != nil {
+		t.Errorf("ConsumeChallenge() error = %v", err)
+	}
+	if consumed {
+		t.Error("ConsumeChallenge() consumed = true, want false")
+	}
+
+	// Метка потраченного осталась
+	spent, err := client.MarkChallengeSpent(ctx, "test-token", time.Minute)
+	if err != nil {
+		t.Errorf("MarkChallengeSpent() error = %v", err)
+	}
+	if spent {
+		t.Error("MarkChallengeSpent() = true, want false")
+	}
+}
@@ -2,13 +2,19 @@ package redis
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
+var ErrChallengeNotFound = errors.New("challenge not found")
+
 type ClientInterface interface {
 	StoreChallenge(ctx context.Context, token string, challenge string, ttl time.Duration) error
 	GetChallenge(ctx context.Context, token string) (string, error)
 	MarkChallengeSpent(ctx context.Context, token string, ttl time.Duration) (bool, error)
+	// ConsumeChallenge за один шаг возвращает challenge, помечает его потраченным
+	// на spentTTL и удаляет. false означает, что challenge уже был потрачен.
+	ConsumeChallenge(ctx context.Context, token string, spentTTL time.Duration) (string, bool, error)
 	DeleteChallenge(ctx context.Context, token string) error
 	Close() error
 }
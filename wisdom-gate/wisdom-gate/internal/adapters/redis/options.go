@@ -0,0 +1,155 @@
+package redis
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var (
+	ErrConflictingTopology = errors.New("sentinel and cluster modes are mutually exclusive")
+	ErrURLWithTopology     = errors.New("redis url is supported only for a single node")
+)
+
+// Options описывает подключение к Redis: одиночный узел (Addr или URL),
+// Sentinel (SentinelMaster + SentinelAddrs) либо Cluster (ClusterAddrs)
+type Options struct {
+	// URL в формате redis:// или rediss://, имеет приоритет над Addr, Username, Password и DB.
+	// Только для одиночного узла: в Sentinel и Cluster его auth и TLS не применились бы
+	URL      string
+	Addr     string
+	Username string
+	Password string
+	DB       int
+
+	TLS                   bool
+	TLSServerName         string
+	TLSInsecureSkipVerify bool
+
+	SentinelMaster   string
+	SentinelAddrs    []string
+	SentinelPassword string
+
+	ClusterAddrs []string
+}
+
+func (o Options) tlsConfig() *tls.Config {
+	if !o.TLS {
+		return nil
+	}
+
+	return &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         o.TLSServerName,
+		InsecureSkipVerify: o.TLSInsecureSkipVerify, //nolint:gosec // явно включается конфигурацией
+	}
+}
+
+// newUniversalClient создаёт клиент под выбранную топологию
+func newUniversalClient(opts Options) (redis.UniversalClient, error) {
+	// Пустая переменная окружения даёт список из одной пустой строки
+	opts.SentinelAddrs = nonEmpty(opts.SentinelAddrs)
+	opts.ClusterAddrs = nonEmpty(opts.ClusterAddrs)
+
+	isSentinel := opts.SentinelMaster != "" || len(opts.SentinelAddrs) > 0
+	isCluster := len(opts.ClusterAddrs) > 0
+
+	switch {
+	case isSentinel && isCluster:
+		return nil, ErrConflictingTopology
+	case opts.URL != "" && (isSentinel || isCluster):
+		return nil, ErrURLWithTopology
+	case isSentinel:
+		failoverOpts, err := failoverOptions(opts)
+		if err != nil {
+			return nil, err
+		}
+		return redis.NewFailoverClient(failoverOpts), nil
+	case isCluster:
+		clusterOpts, err := clusterOptions(opts)
+		if err != nil {
+			return nil, err
+		}
+		return redis.NewClusterClient(clusterOpts), nil
+	default:
+		nodeOpts, err := nodeOptions(opts)
+		if err != nil {
+			return nil, err
+		}
+		return redis.NewClient(nodeOpts), nil
+	}
+}
+
+// nonEmpty возвращает адреса без пробелов по краям и без пустых
+func nonEmpty(addrs []string) []string {
+	var result []string
+	for _, addr := range addrs {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			result = append(result, addr)
+		}
+	}
+	return result
+}
+
+func nodeOptions(opts Options) (*redis.Options, error) {
+	if opts.URL == "" {
+		return &redis.Options{
+			Addr:      opts.Addr,
+			Username:  opts.Username,
+			Password:  opts.Password,
+			DB:        opts.DB,
+			TLSConfig: opts.tlsConfig(),
+		}, nil
+	}
+
+	nodeOpts, err := redis.ParseURL(opts.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis url: %w", err)
+	}
+
+	// rediss:// уже включает TLS, REDIS_TLS позволяет включить его и для redis://
+	if nodeOpts.TLSConfig == nil {
+		nodeOpts.TLSConfig = opts.tlsConfig()
+	}
+	if nodeOpts.TLSConfig != nil {
+		if opts.TLSServerName != "" {
+			nodeOpts.TLSConfig.ServerName = opts.TLSServerName
+		}
+		nodeOpts.TLSConfig.InsecureSkipVerify = opts.TLSInsecureSkipVerify
+	}
+
+	return nodeOpts, nil
+}
+
+func failoverOptions(opts Options) (*redis.FailoverOptions, error) {
+	if opts.SentinelMaster == "" || len(opts.SentinelAddrs) == 0 {
+		return nil, errors.New("sentinel mode requires both master name and sentinel addresses")
+	}
+
+	return &redis.FailoverOptions{
+		MasterName:       opts.SentinelMaster,
+		SentinelAddrs:    opts.SentinelAddrs,
+		SentinelPassword: opts.SentinelPassword,
+		Username:         opts.Username,
+		Password:         opts.Password,
+		DB:               opts.DB,
+		TLSConfig:        opts.tlsConfig(),
+	}, nil
+}
+
+func clusterOptions(opts Options) (*redis.ClusterOptions, error) {
+	// В Cluster есть только база 0, молча игнорировать DB нельзя
+	if opts.DB != 0 {
+		return nil, errors.New("cluster mode does not support database selection")
+	}
+
+	return &redis.ClusterOptions{
+		Addrs:     opts.ClusterAddrs,
+		Username:  opts.Username,
+		Password:  opts.Password,
+		TLSConfig: opts.tlsConfig(),
+	}, nil
+}
@@ -0,0 +1,52 @@
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// slidingWindowScript ведёт скользящее окно в sorted set: score — время запроса
+// в микросекундах по часам Redis, чтобы инстансы не зависели от расхождения своих часов.
+// ARGV[1] — окно в микросекундах, ARGV[2] — лимит, ARGV[3] — уникальный id запроса.
+var slidingWindowScript = redis.NewScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local window = tonumber(ARGV[1])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+if redis.call('ZCARD', KEYS[1]) >= tonumber(ARGV[2]) then
+	return 0
+end
+redis.call('ZADD', KEYS[1], now, now .. '-' .. ARGV[3])
+redis.call('PEXPIRE', KEYS[1], math.ceil(window / 1000))
+return 1
+`)
+
+// RateLimiter — лимитер со скользящим окном в Redis, общий для всех инстансов
+type RateLimiter struct {
+	rdb    redis.UniversalClient
+	limit  int
+	window time.Duration
+}
+
+func NewRateLimiter(client *Client, limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		rdb:    client.rdb,
+		limit:  limit,
+		window: window,
+	}
+}
+
+func (l *RateLimiter) Allow(ctx context.Context, key string) (bool, error) {
+	keys := []string{fmt.Sprintf("ratelimit:{%s}", key)}
+
+	allowed, err := slidingWindowScript.Run(ctx, l.rdb, keys, l.window.Microseconds(), l.limit, rand.Text()).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to check rate limit: %w", err)
+	}
+
+	return allowed == 1, nil
+}
@@ -0,0 +1,82 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// addReputationScript атомарно применяет затухание к счёту и добавляет вес события
+var addReputationScript = redis.NewScript(`
+local data = redis.call('HMGET', KEYS[1], 'score', 'ts')
+local now = tonumber(ARGV[1])
+local score = tonumber(data[1]) or 0
+local ts = tonumber(data[2]) or now
+if now > ts then
+	score = score * math.pow(0.5, (now - ts) / tonumber(ARGV[3]))
+end
+score = score + tonumber(ARGV[2])
+redis.call('HSET', KEYS[1], 'score', tostring(score), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], ARGV[4])
+return tostring(score)
+`)
+
+// reputationTTLHalfLives — через столько периодов полураспада счёт пренебрежимо мал
+const reputationTTLHalfLives = 10
+
+// ReputationStore — хранилище репутации в Redis, общее для всех инстансов
+type ReputationStore struct {
+	rdb redis.UniversalClient
+}
+
+func NewReputationStore(client *Client) *ReputationStore {
+	return &ReputationStore{rdb: client.rdb}
+}
+
+func (s *ReputationStore) Add(ctx context.Context, client string, weight float64, halfLife time.Duration) (float64, error) {
+	key := fmt.Sprintf("reputation:%s", client)
+	ttl := halfLife * reputationTTLHalfLives
+
+	res, err := addReputationScript.Run(ctx, s.rdb, []string{key},
+		time.Now().UnixMilli(), weight, halfLife.Milliseconds(), ttl.Milliseconds()).Text()
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseFloat(res, 64)
+}
+
+func (s *ReputationStore) Score(ctx context.Context, client string, halfLife time.Duration) (float64, error) {
+	key := fmt.Sprintf("reputation:%s", client)
+
+	data, err := s.rdb.HMGet(ctx, key, "score", "ts").Result()
+	if err != nil {
+		return 0, err
+	}
+
+	rawScore, ok := data[0].(string)
+	if !ok {
+		return 0, nil
+	}
+	rawTS, _ := data[1].(string)
+
+	score, err := strconv.ParseFloat(rawScore, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid reputation score: %w", err)
+	}
+	ts, err := strconv.ParseInt(rawTS, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid reputation timestamp: %w", err)
+	}
+
+	elapsed := time.Since(time.UnixMilli(ts))
+	if elapsed <= 0 || halfLife <= 0 {
+		return score, nil
+	}
+
+	return score * math.Exp2(-float64(elapsed)/float64(halfLife)), nil
+}
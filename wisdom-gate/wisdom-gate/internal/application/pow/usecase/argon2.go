@@ -0,0 +1,79 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+
+	"wisdom-gate/pkg/protocol"
+)
+
+// ErrVerifierBusy — все слоты проверки Argon2 заняты; решение не проверялось
+var ErrVerifierBusy = errors.New("argon2 verifier busy")
+
+// DefaultArgon2Params — 8 MiB и один проход: доли секунды на обычном CPU
+var DefaultArgon2Params = protocol.Argon2Params{Memory: 8 * 1024, Time: 1, Threads: 1}
+
+// Argon2Algorithm — memory-hard PoW на Argon2id.
+// Параметры передаются в поле алгоритма заголовка: "argon2id$m=8192,t=1,p=1".
+// Стоимость проверки ограничена: параметры не могут превышать заданные
+// на сервере, а число одновременных вычислений ограничено семафором.
+type Argon2Algorithm struct {
+	params protocol.Argon2Params
+	limits protocol.Argon2Params
+	sem    chan struct{}
+}
+
+func NewArgon2Algorithm(params protocol.Argon2Params, maxConcurrent int) Algorithm {
+	if maxConcurrent < 1 {
+		maxConcurrent = 1
+	}
+
+	return &Argon2Algorithm{
+		params: params,
+		limits: params,
+		sem:    make(chan struct{}, maxConcurrent),
+	}
+}
+
+func (a *Argon2Algorithm) Name() string {
+	return protocol.AlgorithmArgon2id + protocol.AlgorithmParamsSeparator + a.params.String()
+}
+
+func (a *Argon2Algorithm) Hash(data []byte) []byte {
+	a.sem <- struct{}{}
+	defer func() { <-a.sem }()
+
+	return protocol.Argon2Key(data, a.params)
+}
+
+// Verify не ждёт свободного слота: очередь проверок держала бы горутины
+// и соединения, поэтому при занятом семафоре сразу возвращает ErrVerifierBusy
+func (a *Argon2Algorithm) Verify(data []byte, version, difficulty int) (bool, error) {
+	select {
+	case a.sem <- struct{}{}:
+	default:
+		return false, ErrVerifierBusy
+	}
+	defer func() { <-a.sem }()
+
+	return protocol.MeetsDifficulty(protocol.Argon2Key(data, a.params), version, difficulty)
+}
+
+// WithParams возвращает алгоритм с параметрами из заголовка,
+// если они не превышают серверные ограничения
+func (a *Argon2Algorithm) WithParams(params string) (Algorithm, error) {
+	p, err := protocol.ParseArgon2Params(params)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Memory > a.limits.Memory || p.Time > a.limits.Time || p.Threads > a.limits.Threads {
+		return nil, fmt.Errorf("argon2 params %s exceed limits %s", p, a.limits)
+	}
+
+	return &Argon2Algorithm{
+		params: p,
+		limits: a.limits,
+		sem:    a.sem,
+	}, nil
+}
@@ -0,0 +1,136 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"wisdom-gate/pkg/protocol"
+)
+
+var (
+	ErrChallengeSpent = errors.New("challenge already used")
+	ErrBadSignature   = errors.New("challenge signature mismatch")
+)
+
+// StoredChallengeStore хранит каждый выданный challenge в хранилище
+// и сверяет с ним присланное решение.
+type StoredChallengeStore struct {
+	repo         challengeRepoInterface
+	challengeTTL time.Duration
+	spentTTL     time.Duration
+}
+
+func NewStoredChallengeStore(repo challengeRepoInterface, challengeTTL, spentTTL time.Duration) ChallengeStoreInterface {
+	return &StoredChallengeStore{
+		repo:         repo,
+		challengeTTL: challengeTTL,
+		spentTTL:     spentTTL,
+	}
+}
+
+func (s *StoredChallengeStore) Issue(ctx context.Context, header *protocol.HashcashHeader) error {
+	nonce, err := GenerateNonce()
+	if err != nil {
+		return err
+	}
+	header.Nonce = nonce
+
+	if err := s.repo.StoreChallenge(ctx, nonce, header.String(), s.challengeTTL); err != nil {
+		return fmt.Errorf("failed to store challenge: %w", err)
+	}
+
+	return nil
+}
+
+func (s *StoredChallengeStore) Redeem(ctx context.Context, solution *protocol.HashcashHeader) error {
+	// Получение, проверка на повтор, пометка и удаление — одна атомарная операция,
+	// поэтому два одновременных решения одного challenge не пройдут оба
+	stored, consumed, err := s.repo.ConsumeChallenge(ctx, solution.Nonce, s.spentTTL)
+	if err != nil {
+		return fmt.Errorf("failed to consume challenge: %w", err)
+	}
+
+	if !consumed {
+		return ErrChallengeSpent
+	}
+
+	challenge, err := protocol.ParseHashcashHeader(stored)
+	if err != nil {
+		return fmt.Errorf("invalid stored challenge: %w", err)
+	}
+
+	// Решение должно совпадать с выданным challenge во всём, кроме counter
+	if err := solution.MatchChallenge(challenge); err != nil {
+		return fmt.Errorf("challenge mismatch: %w", err)
+	}
+
+	return nil
+}
+
+// StatelessChallengeStore ничего не хранит при выдаче: nonce содержит HMAC
+// над полями заголовка, как SYN cookie. Хранилище нужно только для
+// множества потраченных токенов.
+type StatelessChallengeStore struct {
+	signer   *Signer
+	repo     challengeRepoInterface
+	spentTTL time.Duration
+}
+
+func NewStatelessChallengeStore(signer *Signer, repo challengeRepoInterface, spentTTL time.Duration) ChallengeStoreInterface {
+	return &StatelessChallengeStore{
+		signer:   signer,
+		repo:     repo,
+		spentTTL: spentTTL,
+	}
+}
+
+const nonceTagSeparator = "."
+
+func (s *StatelessChallengeStore) Issue(ctx context.Context, header *protocol.HashcashHeader) error {
+	nonce, err := GenerateNonce()
+	if err != nil {
+		return err
+	}
+
+	header.Nonce = nonce + nonceTagSeparator + s.signer.Sign(signingPayload(header, nonce))
+
+	return nil
+}
+
+func (s *StatelessChallengeStore) Redeem(ctx context.Context, solution *protocol.HashcashHeader) error {
+	nonce, tag, ok := strings.Cut(solution.Nonce, nonceTagSeparator)
+	if !ok || !s.signer.Verify(signingPayload(solution, nonce), tag) {
+		return ErrBadSignature
+	}
+
+	// Подпись действует до ExpiresAt: метка потраченного не должна истечь раньше,
+	// иначе решение можно повторить при SPENT_TTL меньше CHALLENGE_TTL
+	ttl := max(s.spentTTL, time.Until(time.Unix(solution.ExpiresAt, 0)))
+
+	return markSpent(ctx, s.repo, solution.Nonce, ttl)
+}
+
+// signingPayload возвращает заголовок challenge без counter и подписи
+func signingPayload(header *protocol.HashcashHeader, nonce string) string {
+	unsigned := *header
+	unsigned.Nonce = nonce
+	unsigned.Counter = 0
+
+	return unsigned.String()
+}
+
+func markSpent(ctx context.Context, repo challengeRepoInterface, token string, ttl time.Duration) error {
+	marked, err := repo.MarkChallengeSpent(ctx, token, ttl)
+	if err != nil {
+		return fmt.Errorf("failed to check replay: %w", err)
+	}
+
+	if !marked {
+		return ErrChallengeSpent
+	}
+
+	return nil
+}
@@ -0,0 +1,199 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"wisdom-gate/internal/adapters/redis"
+	"wisdom-gate/pkg/protocol"
+)
+
+func newTestHeader() *protocol.HashcashHeader {
+	return &protocol.HashcashHeader{
+		Version:    1,
+		Difficulty: 4,
+		ExpiresAt:  time.Now().Add(time.Minute).Unix(),
+		Subject:    "127.0.0.1:8080",
+		Algorithm:  "sha-256",
+	}
+}
+
+func TestStoredChallengeStore_IssueAndRedeem(t *testing.T) {
+	ctx := context.Background()
+	store := NewStoredChallengeStore(redis.NewMockRedisClient(), time.Minute, time.Minute)
+
+	header := newTestHeader()
+	if err := store.Issue(ctx, header); err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+
+	solution := *header
+	solution.Counter = 42
+	if err := store.Redeem(ctx, &solution); err != nil {
+		t.Fatalf("Redeem() error = %v", err)
+	}
+
+	if err := store.Redeem(ctx, &solution); !errors.Is(err, ErrChallengeSpent) {
+		t.Errorf("Redeem() replay error = %v, want %v", err, ErrChallengeSpent)
+	}
+
+	unknown := *header
+	unknown.Nonce = "unknown-nonce"
+	if err := store.Redeem(ctx, &unknown); !errors.Is(err, redis.ErrChallengeNotFound) {
+		t.Errorf("Redeem() unknown error = %v, want %v", err, redis.ErrChallengeNotFound)
+	}
+}
+
+func TestStatelessChallengeStore_Redeem(t *testing.T) {
+	ctx := context.Background()
+
+	current, err := NewSigner("current-secret", "previous-secret")
+	if err != nil {
+		t.Fatalf("NewSigner() error = %v", err)
+	}
+	previous, err := NewSigner("previous-secret", "")
+	if err != nil {
+		t.Fatalf("NewSigner() error = %v", err)
+	}
+	foreign, err := NewSigner("foreign-secret", "")
+	if err != nil {
+		t.Fatalf("NewSigner() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		issuer  *Signer
+		tamper  func(h *protocol.HashcashHeader)
+		wantErr error
+	}{
+		{
+			name:    "signed with current secret",
+			issuer:  current,
+			tamper:  func(h *protocol.HashcashHeader) {},
+			wantErr: nil,
+		},
+		{
+			name:    "signed with previous secret",
+			issuer:  previous,
+			tamper:  func(h *protocol.HashcashHeader) {},
+			wantErr: nil,
+		},
+		{
+			name:    "signed with unknown secret",
+			issuer:  foreign,
+			tamper:  func(h *protocol.HashcashHeader) {},
+			wantErr: ErrBadSignature,
+		},
+		{
+			name:    "extended expiresAt",
+			issuer:  current,
+			tamper:  func(h *protocol.HashcashHeader) { h.ExpiresAt += 3600 },
+			wantErr: ErrBadSignature,
+		},
+		{
+			name:    "lowered difficulty",
+			issuer:  current,
+			tamper:  func(h *protocol.HashcashHeader) { h.Difficulty = 1 },
+			wantErr: ErrBadSignature,
+		},
+		{
+			name:    "changed subject",
+			issuer:  current,
+			tamper:  func(h *protocol.HashcashHeader) { h.Subject = "10.0.0.1:8080" },
+			wantErr: ErrBadSignature,
+		},
+		{
+			name:    "stripped signature",
+			issuer:  current,
+			tamper:  func(h *protocol.HashcashHeader) { h.Nonce = "bare-nonce" },
+			wantErr: ErrBadSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRedis := redis.NewMockRedisClient()
+			issuer := NewStatelessChallengeStore(tt.issuer, mockRedis, time.Minute)
+			verifier := NewStatelessChallengeStore(current, mockRedis, time.Minute)
+
+			header := newTestHeader()
+			if err := issuer.Issue(ctx, header); err != nil {
+				t.Fatalf("Issue() error = %v", err)
+			}
+
+			solution := *header
+			solution.Counter = 42
+			tt.tamper(&solution)
+
+			if err := verifier.Redeem(ctx, &solution); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Redeem() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStatelessChallengeStore_Replay(t *testing.T) {
+	ctx := context.Background()
+
+	signer, err := NewSigner("secret", "")
+	if err != nil {
+		t.Fatalf("NewSigner() error = %v", err)
+	}
+	store := NewStatelessChallengeStore(signer, redis.NewMockRedisClient(), time.Minute)
+
+	header := newTestHeader()
+	if err := store.Issue(ctx, header); err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+
+	if err := store.Redeem(ctx, header); err != nil {
+		t.Fatalf("Redeem() error = %v", err)
+	}
+
+	if err := store.Redeem(ctx, header); !errors.Is(err, ErrChallengeSpent) {
+		t.Errorf("Redeem() replay error = %v, want %v", err, ErrChallengeSpent)
+	}
+}
+
+// spentTTLRecorder запоминает срок, с которым токен помечен потраченным
+type spentTTLRecorder struct {
+	redis.ClientInterface
+	ttl time.Duration
+}
+
+func (r *spentTTLRecorder) MarkChallengeSpent(ctx context.Context, token string, ttl time.Duration) (bool, error) {
+	r.ttl = ttl
+	return r.ClientInterface.MarkChallengeSpent(ctx, token, ttl)
+}
+
+func TestStatelessChallengeStore_SpentOutlivesChallenge(t *testing.T) {
+	ctx := context.Background()
+
+	signer, err := NewSigner("secret", "")
+	if err != nil {
+		t.Fatalf("NewSigner() error = %v", err)
+	}
+	repo := &spentTTLRecorder{ClientInterface: redis.NewMockRedisClient()}
+	// SPENT_TTL короче срока challenge
+	store := NewStatelessChallengeStore(signer, repo, time.Second)
+
+	header := newTestHeader()
+	if err := store.Issue(ctx, header); err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	if err := store.Redeem(ctx, header); err != nil {
+		t.Fatalf("Redeem() error = %v", err)
+	}
+
+	if until := time.Until(time.Unix(header.ExpiresAt, 0)); repo.ttl < until-time.Second {
+		t.Errorf("spent ttl = %s, want at least %s", repo.ttl, until)
+	}
+}
+
+func TestNewSigner_RequiresSecret(t *testing.T) {
+	if _, err := NewSigner("", "previous"); err == nil {
+		t.Error("NewSigner() error = nil, want error")
+	}
+}
@@ -0,0 +1,196 @@
+package usecase
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const difficultyHistorySize = 256
+
+// LoadThresholds — пороги нагрузки, при превышении любого из которых сложность растёт.
+// Нулевой порог отключает соответствующий сигнал.
+type LoadThresholds struct {
+	ActiveConns int
+	IssueRate   float64 // challenge в секунду
+	FailureRate float64 // доля неудачных проверок, 0..1
+	Latency     time.Duration
+}
+
+// LoadSample — нагрузка за один интервал оценки
+type LoadSample struct {
+	ActiveConns int
+	IssueRate   float64
+	FailureRate float64
+	Latency     time.Duration
+}
+
+// Decision — решение контроллера по итогам интервала
+type Decision struct {
+	At     time.Time
+	From   int
+	To     int
+	Sample LoadSample
+	Reason string
+}
+
+// DifficultyController повышает сложность под нагрузкой и снижает её,
+// когда все сигналы опускаются ниже половины порогов.
+type DifficultyController struct {
+	min        int
+	max        int
+	thresholds LoadThresholds
+
+	level       atomic.Int64
+	activeConns atomic.Int64
+	issued      atomic.Int64
+	verified    atomic.Int64
+	failed      atomic.Int64
+	latencySum  atomic.Int64
+	latencyN    atomic.Int64
+
+	mu        sync.Mutex
+	lastEval  time.Time
+	history   []Decision
+	historyAt int
+}
+
+func NewDifficultyController(initial, minDifficulty, maxDifficulty int, thresholds LoadThresholds) *DifficultyController {
+	if maxDifficulty < minDifficulty {
+		maxDifficulty = minDifficulty
+	}
+
+	c := &DifficultyController{
+		min:        minDifficulty,
+		max:        maxDifficulty,
+		thresholds: thresholds,
+		lastEval:   time.Now(),
+	}
+	c.level.Store(int64(clamp(initial, minDifficulty, maxDifficulty)))
+
+	return c
+}
+
+// Current возвращает сложность для новых challenge
+func (c *DifficultyController) Current() int {
+	return int(c.level.Load())
+}
+
+// Min возвращает нижнюю границу: решения проще неё не принимаются
+func (c *DifficultyController) Min() int {
+	return c.min
+}
+
+func (c *DifficultyController) ConnOpened() {
+	c.activeConns.Add(1)
+}
+
+func (c *DifficultyController) ConnClosed() {
+	c.activeConns.Add(-1)
+}
+
+func (c *DifficultyController) ObserveIssue() {
+	c.issued.Add(1)
+}
+
+func (c *DifficultyController) ObserveVerification(ok bool) {
+	if ok {
+		c.verified.Add(1)
+		return
+	}
+	c.failed.Add(1)
+}
+
+func (c *DifficultyController) ObserveLatency(d time.Duration) {
+	c.latencySum.Add(int64(d))
+	c.latencyN.Add(1)
+}
+
+// Evaluate снимает накопленные за интервал сигналы и корректирует сложность на один шаг
+func (c *DifficultyController) Evaluate(now time.Time) Decision {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elapsed := now.Sub(c.lastEval).Seconds()
+	c.lastEval = now
+
+	sample := LoadSample{ActiveConns: int(c.activeConns.Load())}
+	if elapsed > 0 {
+		sample.IssueRate = float64(c.issued.Swap(0)) / elapsed
+	}
+	verified, failed := c.verified.Swap(0), c.failed.Swap(0)
+	if total := verified + failed; total > 0 {
+		sample.FailureRate = float64(failed) / float64(total)
+	}
+	if n := c.latencyN.Swap(0); n > 0 {
+		sample.Latency = time.Duration(c.latencySum.Swap(0) / n)
+	}
+
+	from := c.Current()
+	to, reason := from, "load within thresholds"
+	if overloaded, why := c.exceeds(sample, 1); overloaded {
+		to, reason = from+1, why
+	} else if calm, _ := c.exceeds(sample, 0.5); !calm {
+		to, reason = from-1, "load below half of thresholds"
+	}
+
+	to = clamp(to, c.min, c.max)
+	c.level.Store(int64(to))
+
+	d := Decision{At: now, From: from, To: to, Sample: sample, Reason: reason}
+	c.record(d)
+
+	return d
+}
+
+// History возвращает последние решения, от старых к новым
+func (c *DifficultyController) History() []Decision {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.history) < difficultyHistorySize {
+		return append([]Decision(nil), c.history...)
+	}
+
+	result := make([]Decision, 0, difficultyHistorySize)
+	result = append(result, c.history[c.historyAt:]...)
+	return append(result, c.history[:c.historyAt]...)
+}
+
+func (c *DifficultyController) record(d Decision) {
+	if len(c.history) < difficultyHistorySize {
+		c.history = append(c.history, d)
+		return
+	}
+
+	c.history[c.historyAt] = d
+	c.historyAt = (c.historyAt + 1) % difficultyHistorySize
+}
+
+// exceeds проверяет, превышает ли хоть один сигнал порог, умноженный на factor
+func (c *DifficultyController) exceeds(s LoadSample, factor float64) (bool, string) {
+	t := c.thresholds
+
+	switch {
+	case t.ActiveConns > 0 && float64(s.ActiveConns) > float64(t.ActiveConns)*factor:
+		return true, "active connections above threshold"
+	case t.IssueRate > 0 && s.IssueRate > t.IssueRate*factor:
+		return true, "challenge issue rate above threshold"
+	case t.FailureRate > 0 && s.FailureRate > t.FailureRate*factor:
+		return true, "verification failure rate above threshold"
+	case t.Latency > 0 && float64(s.Latency) > float64(t.Latency)*factor:
+		return true, "handler latency above threshold"
+	}
+
+	return false, ""
+}
+
+func clamp(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
@@ -0,0 +1,127 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDifficultyController_Evaluate(t *testing.T) {
+	thresholds := LoadThresholds{
+		ActiveConns: 10,
+		IssueRate:   5,
+		FailureRate: 0.5,
+		Latency:     100 * time.Millisecond,
+	}
+
+	tests := []struct {
+		name    string
+		initial int
+		load    func(c *DifficultyController)
+		want    int
+	}{
+		{
+			name:    "idle server lowers difficulty",
+			initial: 20,
+			load:    func(c *DifficultyController) {},
+			want:    19,
+		},
+		{
+			name:    "too many connections raise difficulty",
+			initial: 20,
+			load: func(c *DifficultyController) {
+				for i := 0; i < 11; i++ {
+					c.ConnOpened()
+				}
+			},
+			want: 21,
+		},
+		{
+			name:    "issue rate raises difficulty",
+			initial: 20,
+			load: func(c *DifficultyController) {
+				for i := 0; i < 6; i++ {
+					c.ObserveIssue()
+				}
+			},
+			want: 21,
+		},
+		{
+			name:    "failures raise difficulty",
+			initial: 20,
+			load: func(c *DifficultyController) {
+				c.ObserveVerification(true)
+				c.ObserveVerification(false)
+				c.ObserveVerification(false)
+			},
+			want: 21,
+		},
+		{
+			name:    "slow handlers raise difficulty",
+			initial: 20,
+			load: func(c *DifficultyController) {
+				c.ObserveLatency(300 * time.Millisecond)
+			},
+			want: 21,
+		},
+		{
+			name:    "moderate load holds difficulty",
+			initial: 20,
+			load: func(c *DifficultyController) {
+				for i := 0; i < 7; i++ {
+					c.ConnOpened()
+				}
+			},
+			want: 20,
+		},
+		{
+			name:    "upper bound",
+			initial: 24,
+			load: func(c *DifficultyController) {
+				c.ObserveLatency(time.Second)
+			},
+			want: 24,
+		},
+		{
+			name:    "lower bound",
+			initial: 16,
+			load:    func(c *DifficultyController) {},
+			want:    16,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewDifficultyController(tt.initial, 16, 24, thresholds)
+			tt.load(c)
+
+			d := c.Evaluate(time.Now().Add(time.Second))
+			if d.To != tt.want || c.Current() != tt.want {
+				t.Errorf("Evaluate() = %d, Current() = %d, want %d (%s)", d.To, c.Current(), tt.want, d.Reason)
+			}
+		})
+	}
+}
+
+func TestDifficultyController_History(t *testing.T) {
+	c := NewDifficultyController(20, 16, 24, LoadThresholds{ActiveConns: 1})
+
+	start := time.Now()
+	for i := 1; i <= difficultyHistorySize+3; i++ {
+		c.Evaluate(start.Add(time.Duration(i) * time.Second))
+	}
+
+	history := c.History()
+	if len(history) != difficultyHistorySize {
+		t.Fatalf("History() length = %d, want %d", len(history), difficultyHistorySize)
+	}
+
+	for i := 1; i < len(history); i++ {
+		if !history[i].At.After(history[i-1].At) {
+			t.Fatalf("History() is not ordered at %d", i)
+		}
+	}
+
+	if got := history[len(history)-1].To; got != c.Current() {
+		t.Errorf("last decision = %d, want %d", got, c.Current())
+	}
+}
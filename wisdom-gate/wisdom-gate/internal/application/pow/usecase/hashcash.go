@@ -2,25 +2,25 @@ package usecase
 
 import (
 	"crypto/rand"
-	"crypto/sha256"
 	"encoding/base64"
-	"encoding/hex"
 	"fmt"
-	"strings"
 )
 
-type Verifier struct{}
+type Verifier struct {
+	registry *Registry
+}
 
-func NewVerifier() VerifierInterface {
-	return &Verifier{}
+func NewVerifier(registry *Registry) VerifierInterface {
+	return &Verifier{registry: registry}
 }
 
-func (v *Verifier) VerifySolution(header string, difficulty int) (bool, error) {
-	hash := sha256.Sum256([]byte(header))
-	hashStr := hex.EncodeToString(hash[:])
+func (v *Verifier) VerifySolution(header, algorithm string, version, difficulty int) (bool, error) {
+	alg, err := v.registry.Get(algorithm)
+	if err != nil {
+		return false, err
+	}
 
-	requiredZeros := strings.Repeat("0", difficulty)
-	return strings.HasPrefix(hashStr, requiredZeros), nil
+	return alg.Verify([]byte(header), version, difficulty)
 }
 
 type NonceGenerator struct{}
@@ -2,14 +2,22 @@ package usecase
 
 import (
 	"testing"
+
+	"wisdom-gate/pkg/protocol"
 )
 
 func TestVerifier_VerifySolution(t *testing.T) {
-	verifier := NewVerifier()
+	registry, err := NewRegistry(protocol.AlgorithmSha256, protocol.AlgorithmSha512)
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+	verifier := NewVerifier(registry)
 
 	tests := []struct {
 		name       string
 		header     string
+		algorithm  string
+		version    int
 		difficulty int
 		want       bool
 		wantErr    bool
@@ -17,6 +25,8 @@ func TestVerifier_VerifySolution(t *testing.T) {
 		{
 			name:       "valid solution with difficulty 1",
 			header:     "1:1:1234567890:127.0.0.1:8080:sha-256:test-nonce:1",
+			algorithm:  protocol.AlgorithmSha256,
+			version:    1,
 			difficulty: 1,
 			want:       false,
 			wantErr:    false,
@@ -24,6 +34,8 @@ func TestVerifier_VerifySolution(t *testing.T) {
 		{
 			name:       "invalid solution",
 			header:     "invalid-header",
+			algorithm:  protocol.AlgorithmSha256,
+			version:    1,
 			difficulty: 4,
 			want:       false,
 			wantErr:    false,
@@ -31,15 +43,91 @@ func TestVerifier_VerifySolution(t *testing.T) {
 		{
 			name:       "empty header",
 			header:     "",
+			algorithm:  protocol.AlgorithmSha256,
+			version:    1,
 			difficulty: 4,
 			want:       false,
 			wantErr:    false,
 		},
+		{
+			// sha256 = 050f7a...: 5 нулевых бит, 1 нулевой hex-символ
+			name:       "hex difficulty satisfied",
+			header:     "2:5:1234567890:127.0.0.1:sha-256:test-nonce:37",
+			algorithm:  protocol.AlgorithmSha256,
+			version:    1,
+			difficulty: 1,
+			want:       true,
+			wantErr:    false,
+		},
+		{
+			name:       "hex difficulty not satisfied",
+			header:     "2:5:1234567890:127.0.0.1:sha-256:test-nonce:37",
+			algorithm:  protocol.AlgorithmSha256,
+			version:    1,
+			difficulty: 2,
+			want:       false,
+			wantErr:    false,
+		},
+		{
+			name:       "bit difficulty satisfied",
+			header:     "2:5:1234567890:127.0.0.1:sha-256:test-nonce:37",
+			algorithm:  protocol.AlgorithmSha256,
+			version:    2,
+			difficulty: 5,
+			want:       true,
+			wantErr:    false,
+		},
+		{
+			name:       "bit difficulty not satisfied",
+			header:     "2:5:1234567890:127.0.0.1:sha-256:test-nonce:37",
+			algorithm:  protocol.AlgorithmSha256,
+			version:    2,
+			difficulty: 6,
+			want:       false,
+			wantErr:    false,
+		},
+		{
+			// sha256 = 007f8b...: 9 нулевых бит
+			name:       "bit difficulty between hex levels",
+			header:     "2:5:1234567890:127.0.0.1:sha-256:test-nonce:766",
+			algorithm:  protocol.AlgorithmSha256,
+			version:    2,
+			difficulty: 9,
+			want:       true,
+			wantErr:    false,
+		},
+		{
+			name:       "disabled algorithm",
+			header:     "2:5:1234567890:127.0.0.1:sha-256:test-nonce:37",
+			algorithm:  protocol.AlgorithmBlake2b,
+			version:    2,
+			difficulty: 0,
+			want:       false,
+			wantErr:    true,
+		},
+		{
+			name:       "unknown algorithm",
+			header:     "2:5:1234567890:127.0.0.1:sha-256:test-nonce:37",
+			algorithm:  "md5",
+			version:    2,
+			difficulty: 0,
+			want:       false,
+			wantErr:    true,
+		},
+		{
+			name:       "unsupported version",
+			header:     "7:5:1234567890:127.0.0.1:sha-256:test-nonce:37",
+			algorithm:  protocol.AlgorithmSha256,
+			version:    7,
+			difficulty: 5,
+			want:       false,
+			wantErr:    true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := verifier.VerifySolution(tt.header, tt.difficulty)
+			got, err := verifier.VerifySolution(tt.header, tt.algorithm, tt.version, tt.difficulty)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Verifier.VerifySolution() error = %v, wantErr %v", err, tt.wantErr)
 				return
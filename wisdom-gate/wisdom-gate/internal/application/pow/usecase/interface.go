@@ -1,9 +1,29 @@
 package usecase
 
+import (
+	"context"
+	"time"
+
+	"wisdom-gate/pkg/protocol"
+)
+
 type VerifierInterface interface {
-	VerifySolution(header string, difficulty int) (bool, error)
+	VerifySolution(header, algorithm string, version, difficulty int) (bool, error)
+	VerifyStamp(stamp string, bits int) bool
 }
 
 type NonceGeneratorInterface interface {
 	GenerateNonce() (string, error)
 }
+
+// ChallengeStoreInterface выдаёт challenge и погашает присланные решения
+type ChallengeStoreInterface interface {
+	Issue(ctx context.Context, header *protocol.HashcashHeader) error
+	Redeem(ctx context.Context, solution *protocol.HashcashHeader) error
+}
+
+type challengeRepoInterface interface {
+	StoreChallenge(ctx context.Context, token string, challenge string, ttl time.Duration) error
+	ConsumeChallenge(ctx context.Context, token string, spentTTL time.Duration) (string, bool, error)
+	MarkChallengeSpent(ctx context.Context, token string, ttl time.Duration) (bool, error)
+}
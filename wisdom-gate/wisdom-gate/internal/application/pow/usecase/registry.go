@@ -0,0 +1,172 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"wisdom-gate/pkg/protocol"
+)
+
+var (
+	ErrUnknownAlgorithm    = errors.New("unknown algorithm")
+	ErrAlgorithmNotAllowed = errors.New("algorithm not allowed")
+)
+
+// Algorithm — алгоритм PoW: хешер и проверка решения заданной сложности
+type Algorithm interface {
+	Name() string
+	Hash(data []byte) []byte
+	Verify(data []byte, version, difficulty int) (bool, error)
+}
+
+// ParameterizedAlgorithm — алгоритм, параметры которого передаются в заголовке
+type ParameterizedAlgorithm interface {
+	Algorithm
+	WithParams(params string) (Algorithm, error)
+}
+
+// digestAlgorithm — алгоритм на основе обычной хеш-функции:
+// решение принимается по числу ведущих нулей дайджеста
+type digestAlgorithm struct {
+	name string
+	sum  func(data []byte) []byte
+}
+
+func NewDigestAlgorithm(name string, sum func(data []byte) []byte) Algorithm {
+	return &digestAlgorithm{name: name, sum: sum}
+}
+
+func (a *digestAlgorithm) Name() string {
+	return a.name
+}
+
+func (a *digestAlgorithm) Hash(data []byte) []byte {
+	return a.sum(data)
+}
+
+func (a *digestAlgorithm) Verify(data []byte, version, difficulty int) (bool, error) {
+	return protocol.MeetsDifficulty(a.sum(data), version, difficulty)
+}
+
+// Registry хранит известные алгоритмы и список разрешённых на сервере
+type Registry struct {
+	algorithms map[string]Algorithm
+	enabled    []string
+}
+
+// NewRegistry регистрирует встроенные алгоритмы и разрешает перечисленные.
+// Первый из enabled используется по умолчанию.
+func NewRegistry(enabled ...string) (*Registry, error) {
+	r := &Registry{algorithms: make(map[string]Algorithm)}
+
+	for _, name := range protocol.DigestAlgorithms {
+		sum, _ := protocol.Digest(name)
+		r.Register(NewDigestAlgorithm(name, sum))
+	}
+	r.Register(NewArgon2Algorithm(DefaultArgon2Params, 1))
+
+	if len(enabled) == 0 {
+		return nil, fmt.Errorf("at least one algorithm must be enabled")
+	}
+
+	for _, name := range enabled {
+		if err := r.Enable(name); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// Register добавляет алгоритм, не разрешая его.
+// Алгоритм с тем же именем заменяется, так можно переопределить параметры встроенного.
+func (r *Registry) Register(alg Algorithm) {
+	r.algorithms[baseName(alg.Name())] = alg
+}
+
+func (r *Registry) Enable(name string) error {
+	if _, ok := r.algorithms[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownAlgorithm, name)
+	}
+
+	for _, enabled := range r.enabled {
+		if enabled == name {
+			return nil
+		}
+	}
+
+	r.enabled = append(r.enabled, name)
+	return nil
+}
+
+// Enabled возвращает разрешённые алгоритмы в порядке предпочтения сервера
+func (r *Registry) Enabled() []string {
+	return append([]string(nil), r.enabled...)
+}
+
+// Get возвращает алгоритм, только если он разрешён.
+// Имя может содержать параметры: "argon2id$m=8192,t=1,p=1".
+func (r *Registry) Get(name string) (Algorithm, error) {
+	base, params, hasParams := strings.Cut(name, protocol.AlgorithmParamsSeparator)
+
+	alg, ok := r.algorithms[base]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAlgorithm, base)
+	}
+
+	if !r.isEnabled(base) {
+		return nil, fmt.Errorf("%w: %s", ErrAlgorithmNotAllowed, base)
+	}
+
+	if !hasParams {
+		return alg, nil
+	}
+
+	parameterized, ok := alg.(ParameterizedAlgorithm)
+	if !ok {
+		return nil, fmt.Errorf("algorithm %s does not take params", base)
+	}
+
+	return parameterized.WithParams(params)
+}
+
+func (r *Registry) isEnabled(base string) bool {
+	for _, enabled := range r.enabled {
+		if enabled == base {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Select выбирает первый разрешённый сервером алгоритм из поддерживаемых клиентом
+// и возвращает его полное имя вместе с параметрами.
+// Пустой список означает алгоритм по умолчанию.
+func (r *Registry) Select(supported []string) (string, error) {
+	if len(supported) == 0 {
+		return r.algorithms[r.enabled[0]].Name(), nil
+	}
+
+	for _, enabled := range r.enabled {
+		for _, name := range supported {
+			if name == enabled {
+				return r.algorithms[enabled].Name(), nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("%w: allowed %s", ErrAlgorithmNotAllowed, strings.Join(r.enabled, ","))
+}
+
+// baseName отбрасывает параметры алгоритма
+func baseName(name string) string {
+	base, _, _ := strings.Cut(name, protocol.AlgorithmParamsSeparator)
+	return base
+}
+
+// IsMemoryHard сообщает, что алгоритм требует отдельной шкалы сложности
+func IsMemoryHard(algorithm string) bool {
+	return baseName(algorithm) == protocol.AlgorithmArgon2id
+}
@@ -0,0 +1,176 @@
+package usecase
+
+import (
+	"errors"
+	"testing"
+
+	"wisdom-gate/pkg/protocol"
+)
+
+func TestNewRegistry(t *testing.T) {
+	if _, err := NewRegistry(); err == nil {
+		t.Error("NewRegistry() error = nil, want error")
+	}
+
+	if _, err := NewRegistry("md5"); !errors.Is(err, ErrUnknownAlgorithm) {
+		t.Errorf("NewRegistry() error = %v, want %v", err, ErrUnknownAlgorithm)
+	}
+}
+
+func TestRegistry_Get(t *testing.T) {
+	registry, err := NewRegistry(protocol.AlgorithmSha3256)
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		alg     string
+		wantErr error
+	}{
+		{name: "enabled algorithm", alg: protocol.AlgorithmSha3256, wantErr: nil},
+		{name: "registered but disabled", alg: protocol.AlgorithmSha256, wantErr: ErrAlgorithmNotAllowed},
+		{name: "unknown algorithm", alg: "md5", wantErr: ErrUnknownAlgorithm},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := registry.Get(tt.alg); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Registry.Get() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRegistry_Select(t *testing.T) {
+	registry, err := NewRegistry(protocol.AlgorithmBlake2b, protocol.AlgorithmSha256)
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		supported []string
+		want      string
+		wantErr   bool
+	}{
+		{name: "no preference", supported: nil, want: protocol.AlgorithmBlake2b},
+		{name: "server order wins", supported: []string{protocol.AlgorithmSha256, protocol.AlgorithmBlake2b}, want: protocol.AlgorithmBlake2b},
+		{name: "single common algorithm", supported: []string{protocol.AlgorithmSha512, protocol.AlgorithmSha256}, want: protocol.AlgorithmSha256},
+		{name: "nothing in common", supported: []string{protocol.AlgorithmSha512}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := registry.Select(tt.supported)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Registry.Select() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Registry.Select() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegistry_BuiltinAlgorithms(t *testing.T) {
+	names := []string{protocol.AlgorithmSha256, protocol.AlgorithmSha512, protocol.AlgorithmBlake2b, protocol.AlgorithmSha3256}
+
+	registry, err := NewRegistry(names...)
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			alg, err := registry.Get(name)
+			if err != nil {
+				t.Fatalf("Registry.Get() error = %v", err)
+			}
+
+			if len(alg.Hash([]byte("wisdom"))) == 0 {
+				t.Error("Algorithm.Hash() returned empty digest")
+			}
+
+			valid, err := alg.Verify([]byte("wisdom"), protocol.HeaderVersionBits, 0)
+			if err != nil || !valid {
+				t.Errorf("Algorithm.Verify() = %v, %v, want true, nil", valid, err)
+			}
+		})
+	}
+}
+
+func TestRegistry_Argon2Params(t *testing.T) {
+	registry, err := NewRegistry(protocol.AlgorithmArgon2id)
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+	registry.Register(NewArgon2Algorithm(protocol.Argon2Params{Memory: 64, Time: 2, Threads: 1}, 2))
+
+	name, err := registry.Select([]string{protocol.AlgorithmArgon2id})
+	if err != nil {
+		t.Fatalf("Registry.Select() error = %v", err)
+	}
+	if name != "argon2id$m=64,t=2,p=1" {
+		t.Errorf("Registry.Select() = %v, want %v", name, "argon2id$m=64,t=2,p=1")
+	}
+
+	tests := []struct {
+		name    string
+		alg     string
+		wantErr bool
+	}{
+		{name: "issued params", alg: "argon2id$m=64,t=2,p=1", wantErr: false},
+		{name: "cheaper params", alg: "argon2id$m=32,t=1,p=1", wantErr: false},
+		{name: "memory above limit", alg: "argon2id$m=1048576,t=2,p=1", wantErr: true},
+		{name: "time above limit", alg: "argon2id$m=64,t=100,p=1", wantErr: true},
+		{name: "threads above limit", alg: "argon2id$m=64,t=2,p=8", wantErr: true},
+		{name: "missing param", alg: "argon2id$m=64,t=2", wantErr: true},
+		{name: "unknown param", alg: "argon2id$m=64,t=2,p=1,x=1", wantErr: true},
+		{name: "params for digest algorithm", alg: "sha-256$m=64", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := registry.Get(tt.alg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Registry.Get() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestArgon2Algorithm_Verify(t *testing.T) {
+	alg := NewArgon2Algorithm(protocol.Argon2Params{Memory: 64, Time: 1, Threads: 1}, 1)
+
+	digest := alg.Hash([]byte("wisdom"))
+	if len(digest) != protocol.Argon2KeyLen {
+		t.Fatalf("Argon2Algorithm.Hash() length = %d, want %d", len(digest), protocol.Argon2KeyLen)
+	}
+
+	valid, err := alg.Verify([]byte("wisdom"), protocol.HeaderVersionBits, 0)
+	if err != nil || !valid {
+		t.Errorf("Argon2Algorithm.Verify() = %v, %v, want true, nil", valid, err)
+	}
+
+	valid, err = alg.Verify([]byte("wisdom"), protocol.HeaderVersionBits, protocol.Argon2KeyLen*8+1)
+	if err != nil || valid {
+		t.Errorf("Argon2Algorithm.Verify() = %v, %v, want false, nil", valid, err)
+	}
+}
+
+func TestArgon2Algorithm_VerifyBusy(t *testing.T) {
+	alg := NewArgon2Algorithm(protocol.Argon2Params{Memory: 64, Time: 1, Threads: 1}, 1)
+	// Занимаем единственный слот, как это сделала бы параллельная проверка
+	alg.(*Argon2Algorithm).sem <- struct{}{}
+
+	valid, err := alg.Verify([]byte("wisdom"), protocol.HeaderVersionBits, 0)
+	if !errors.Is(err, ErrVerifierBusy) || valid {
+		t.Errorf("Argon2Algorithm.Verify() = %v, %v, want false, %v", valid, err, ErrVerifierBusy)
+	}
+
+	<-alg.(*Argon2Algorithm).sem
+	if valid, err := alg.Verify([]byte("wisdom"), protocol.HeaderVersionBits, 0); err != nil || !valid {
+		t.Errorf("Argon2Algorithm.Verify() after release = %v, %v, want true, nil", valid, err)
+	}
+}
@@ -0,0 +1,51 @@
+package usecase
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+)
+
+// Signer подписывает challenge по HMAC-SHA256.
+// Для ротации ключей проверка принимает как текущий, так и предыдущий секрет.
+type Signer struct {
+	current  []byte
+	previous []byte
+}
+
+func NewSigner(current, previous string) (*Signer, error) {
+	if current == "" {
+		return nil, fmt.Errorf("hmac secret is required")
+	}
+
+	s := &Signer{current: []byte(current)}
+	if previous != "" {
+		s.previous = []byte(previous)
+	}
+
+	return s, nil
+}
+
+func (s *Signer) Sign(payload string) string {
+	return base64.RawURLEncoding.EncodeToString(mac(s.current, payload))
+}
+
+func (s *Signer) Verify(payload, tag string) bool {
+	sig, err := base64.RawURLEncoding.DecodeString(tag)
+	if err != nil {
+		return false
+	}
+
+	if hmac.Equal(sig, mac(s.current, payload)) {
+		return true
+	}
+
+	return s.previous != nil && hmac.Equal(sig, mac(s.previous, payload))
+}
+
+func mac(key []byte, payload string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
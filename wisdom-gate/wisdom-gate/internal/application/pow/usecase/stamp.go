@@ -0,0 +1,108 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha1" //nolint:gosec // SHA-1 задан спецификацией Hashcash v1
+	"errors"
+	"fmt"
+	"time"
+
+	"wisdom-gate/pkg/protocol"
+)
+
+var (
+	ErrStampExpired          = errors.New("stamp expired")
+	ErrStampFromFuture       = errors.New("stamp date is in the future")
+	ErrStampResourceMismatch = errors.New("stamp resource mismatch")
+)
+
+// stampClockSkew — допустимое расхождение часов клиента и сервера
+const stampClockSkew = 2 * time.Minute
+
+// StampStore выдаёт и погашает марки Hashcash v1. Марки, выпущенные сторонними
+// утилитами, сервер при выдаче не видел, поэтому, как и требует спецификация,
+// проверяются ресурс, дата и повторное использование: потраченная марка
+// помнится, пока её дата не выйдет из окна действия, но не дольше maxSpentTTL.
+// Марка с датой YYMMDD действует до конца суток, и без предела множество
+// потраченных росло бы на сутки вперёд; по истечении предела такую марку
+// можно предъявить повторно.
+type StampStore struct {
+	repo        challengeRepoInterface
+	resource    string
+	validity    time.Duration
+	maxSpentTTL time.Duration
+	now         func() time.Time
+}
+
+func NewStampStore(repo challengeRepoInterface, resource string, validity, maxSpentTTL time.Duration) (*StampStore, error) {
+	if validity <= 0 {
+		return nil, fmt.Errorf("stamp validity must be positive, got %s", validity)
+	}
+	if maxSpentTTL <= 0 {
+		return nil, fmt.Errorf("stamp spent ttl must be positive, got %s", maxSpentTTL)
+	}
+
+	return &StampStore{
+		repo:        repo,
+		resource:    resource,
+		validity:    validity,
+		maxSpentTTL: maxSpentTTL,
+		now:         time.Now,
+	}, nil
+}
+
+// Issue возвращает шаблон марки с пустым counter: клиент подбирает только его,
+// а стандартной утилите достаточно bits и resource
+func (s *StampStore) Issue(bits int) (*protocol.HashcashStamp, error) {
+	random, err := GenerateNonce()
+	if err != nil {
+		return nil, err
+	}
+
+	return &protocol.HashcashStamp{
+		Version:  protocol.HashcashStampVersion,
+		Bits:     bits,
+		Date:     protocol.FormatStampDate(s.now()),
+		Resource: s.resource,
+		Rand:     random,
+	}, nil
+}
+
+// Validity — окно действия марки с даты выдачи
+func (s *StampStore) Validity() time.Duration {
+	return s.validity
+}
+
+func (s *StampStore) Redeem(ctx context.Context, stamp *protocol.HashcashStamp) error {
+	if stamp.Resource != s.resource {
+		return ErrStampResourceMismatch
+	}
+
+	start, end, err := stamp.DatePeriod()
+	if err != nil {
+		return err
+	}
+
+	now := s.now()
+	if start.After(now.Add(stampClockSkew)) {
+		return ErrStampFromFuture
+	}
+
+	// Марка действительна, пока с конца периода её даты прошло не больше validity
+	expiresAt := end.Add(s.validity)
+	if !now.Before(expiresAt) {
+		return ErrStampExpired
+	}
+
+	ttl := min(expiresAt.Sub(now)+stampClockSkew, s.maxSpentTTL)
+
+	return markSpent(ctx, s.repo, "stamp:"+stamp.String(), ttl)
+}
+
+// VerifyStamp проверяет, что SHA-1 марки начинается с bits нулевых бит.
+// Хеш считается от строки в том виде, в каком её прислал клиент
+func (v *Verifier) VerifyStamp(stamp string, bits int) bool {
+	digest := sha1.Sum([]byte(stamp)) //nolint:gosec // SHA-1 задан спецификацией Hashcash v1
+
+	return protocol.HasLeadingZeroBits(digest[:], bits)
+}
@@ -0,0 +1,164 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"wisdom-gate/internal/adapters/redis"
+	"wisdom-gate/pkg/protocol"
+)
+
+func TestVerifier_VerifyStamp(t *testing.T) {
+	registry, err := NewRegistry(protocol.AlgorithmSha256)
+	if err != nil {
+		t.Fatalf("NewRegistry(protocol.AlgorithmSha256) error = %v", err)
+	}
+	verifier := NewVerifier(registry)
+
+	// Марки из документации hashcash.org, выпущенные утилитой hashcash
+	stamps := []string{
+		"1:20:060408:adam@cypherspace.org::1QTjaYd7niiQA/sc:ePa",
+		"1:20:1303030600:adam@cypherspace.org::McMybZIhxKXu57jd:ckvi",
+	}
+	for _, stamp := range stamps {
+		if !verifier.VerifyStamp(stamp, 20) {
+			t.Errorf("VerifyStamp(%q, 20) = false, want true", stamp)
+		}
+		if verifier.VerifyStamp(stamp, 24) {
+			t.Errorf("VerifyStamp(%q, 24) = true, want false", stamp)
+		}
+	}
+}
+
+func TestStampStore_Redeem(t *testing.T) {
+	now := time.Date(2025, 10, 17, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		stamp   protocol.HashcashStamp
+		wantErr error
+	}{
+		{
+			name:  "issued just now",
+			stamp: protocol.HashcashStamp{Version: 1, Bits: 20, Date: "251017120000", Resource: "wisdom-gate", Rand: "r1"},
+		},
+		{
+			name:  "day precision from a tool",
+			stamp: protocol.HashcashStamp{Version: 1, Bits: 20, Date: "251017", Resource: "wisdom-gate", Rand: "r2"},
+		},
+		{
+			name:    "other resource",
+			stamp:   protocol.HashcashStamp{Version: 1, Bits: 20, Date: "251017120000", Resource: "other", Rand: "r3"},
+			wantErr: ErrStampResourceMismatch,
+		},
+		{
+			name:    "expired",
+			stamp:   protocol.HashcashStamp{Version: 1, Bits: 20, Date: "251017115900", Resource: "wisdom-gate", Rand: "r4"},
+			wantErr: ErrStampExpired,
+		},
+		{
+			name:    "from the future",
+			stamp:   protocol.HashcashStamp{Version: 1, Bits: 20, Date: "251018", Resource: "wisdom-gate", Rand: "r5"},
+			wantErr: ErrStampFromFuture,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStampStore(t, redis.NewMockRedisClient())
+			store.now = func() time.Time { return now }
+
+			err := store.Redeem(ctx, &tt.stamp)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Redeem() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if err := store.Redeem(ctx, &tt.stamp); !errors.Is(err, ErrChallengeSpent) {
+				t.Errorf("Redeem() replay error = %v, want %v", err, ErrChallengeSpent)
+			}
+		})
+	}
+}
+
+func TestStampStore_SpentTTL(t *testing.T) {
+	now := time.Date(2025, 10, 17, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		date string
+		want time.Duration
+	}{
+		{name: "second precision", date: "251017120000", want: time.Second + 20*time.Second + stampClockSkew},
+		{name: "day precision capped", date: "251017", want: time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &spentTTLRecorder{ClientInterface: redis.NewMockRedisClient()}
+			store := newTestStampStore(t, repo)
+			store.now = func() time.Time { return now }
+
+			stamp := protocol.HashcashStamp{Version: 1, Bits: 20, Date: tt.date, Resource: "wisdom-gate", Rand: "r1"}
+			if err := store.Redeem(ctx, &stamp); err != nil {
+				t.Fatalf("Redeem() error = %v", err)
+			}
+			if repo.ttl != tt.want {
+				t.Errorf("spent ttl = %s, want %s", repo.ttl, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewStampStore_InvalidDurations(t *testing.T) {
+	tests := []struct {
+		name                  string
+		validity, maxSpentTTL time.Duration
+	}{
+		{name: "zero validity", validity: 0, maxSpentTTL: time.Hour},
+		{name: "zero spent ttl", validity: 20 * time.Second, maxSpentTTL: 0},
+		{name: "negative spent ttl", validity: 20 * time.Second, maxSpentTTL: -time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewStampStore(redis.NewMockRedisClient(), "wisdom-gate", tt.validity, tt.maxSpentTTL); err == nil {
+				t.Error("NewStampStore() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestStampStore_Issue(t *testing.T) {
+	store := newTestStampStore(t, redis.NewMockRedisClient())
+
+	stamp, err := store.Issue(22)
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+
+	parsed, err := protocol.ParseHashcashStamp(stamp.String())
+	if err != nil {
+		t.Fatalf("ParseHashcashStamp(%q) error = %v", stamp.String(), err)
+	}
+	if parsed.Bits != 22 || parsed.Resource != "wisdom-gate" || parsed.Rand == "" || parsed.Counter != "" {
+		t.Errorf("Issue() = %+v, want template for wisdom-gate with 22 bits", parsed)
+	}
+}
+
+func newTestStampStore(t *testing.T, repo challengeRepoInterface) *StampStore {
+	t.Helper()
+
+	store, err := NewStampStore(repo, "wisdom-gate", 20*time.Second, time.Hour)
+	if err != nil {
+		t.Fatalf("NewStampStore() error = %v", err)
+	}
+
+	return store
+}
@@ -1,14 +0,0 @@
-package consts
-
-const (
-	CmdREQ  = "REQ"
-	CmdRES  = "RES"
-	CmdCHL  = "CHL"
-	CmdERR  = "ERR"
-	CmdDISC = "DISC"
-	CmdQOT  = "QOT"
-)
-
-const (
-	AlgorithmSha256 = "sha-256"
-)
@@ -0,0 +1,12 @@
+package usecase
+
+import (
+	"context"
+	"time"
+)
+
+// storeInterface хранит затухающий счёт нарушений клиента
+type storeInterface interface {
+	Add(ctx context.Context, client string, weight float64, halfLife time.Duration) (float64, error)
+	Score(ctx context.Context, client string, halfLife time.Duration) (float64, error)
+}
@@ -0,0 +1,71 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type Event string
+
+const (
+	EventFailedVerification Event = "failed_verification"
+	EventExpiredChallenge   Event = "expired_challenge"
+	EventReplay             Event = "replay"
+	EventRateLimited        Event = "rate_limited"
+)
+
+// eventWeights — вклад события в счёт клиента. Повтор потраченного
+// challenge почти наверняка злонамерен, истечение — часто просто медленный клиент.
+var eventWeights = map[Event]float64{
+	EventFailedVerification: 1,
+	EventExpiredChallenge:   0.5,
+	EventReplay:             4,
+	EventRateLimited:        1,
+}
+
+// ReputationUseCase переводит историю нарушений клиента в надбавку к сложности PoW.
+// Счёт затухает экспоненциально с периодом полураспада halfLife.
+type ReputationUseCase struct {
+	store       storeInterface
+	halfLife    time.Duration
+	penaltyStep float64
+	maxPenalty  int
+}
+
+func NewReputationUseCase(store storeInterface, halfLife time.Duration, penaltyStep float64, maxPenalty int) *ReputationUseCase {
+	return &ReputationUseCase{
+		store:       store,
+		halfLife:    halfLife,
+		penaltyStep: penaltyStep,
+		maxPenalty:  maxPenalty,
+	}
+}
+
+func (u *ReputationUseCase) Record(ctx context.Context, client string, event Event) error {
+	weight, ok := eventWeights[event]
+	if !ok {
+		return fmt.Errorf("unknown reputation event: %s", event)
+	}
+
+	if _, err := u.store.Add(ctx, client, weight, u.halfLife); err != nil {
+		return fmt.Errorf("failed to record %s: %w", event, err)
+	}
+
+	return nil
+}
+
+// Penalty возвращает надбавку к сложности в битах: каждый бит удваивает
+// стоимость решения, поэтому линейный рост надбавки даёт экспоненциальный рост цены
+func (u *ReputationUseCase) Penalty(ctx context.Context, client string) (int, error) {
+	if u.maxPenalty <= 0 || u.penaltyStep <= 0 {
+		return 0, nil
+	}
+
+	score, err := u.store.Score(ctx, client, u.halfLife)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get reputation: %w", err)
+	}
+
+	return min(int(score/u.penaltyStep), u.maxPenalty), nil
+}
@@ -0,0 +1,100 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type mockStore struct {
+	scores map[string]float64
+	err    error
+}
+
+func (m *mockStore) Add(ctx context.Context, client string, weight float64, halfLife time.Duration) (float64, error) {
+	if m.err != nil {
+		return 0, m.err
+	}
+	m.scores[client] += weight
+	return m.scores[client], nil
+}
+
+func (m *mockStore) Score(ctx context.Context, client string, halfLife time.Duration) (float64, error) {
+	if m.err != nil {
+		return 0, m.err
+	}
+	return m.scores[client], nil
+}
+
+func TestReputationUseCase_Penalty(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []Event
+		want   int
+	}{
+		{
+			name:   "clean client",
+			events: nil,
+			want:   0,
+		},
+		{
+			name:   "single expired challenge",
+			events: []Event{EventExpiredChallenge},
+			want:   0,
+		},
+		{
+			name:   "repeated failures",
+			events: []Event{EventFailedVerification, EventFailedVerification, EventRateLimited, EventFailedVerification},
+			want:   2,
+		},
+		{
+			name:   "replays are capped",
+			events: []Event{EventReplay, EventReplay, EventReplay, EventReplay, EventReplay},
+			want:   8,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := NewReputationUseCase(&mockStore{scores: map[string]float64{}}, time.Minute, 2, 8)
+			ctx := context.Background()
+
+			for _, event := range tt.events {
+				if err := uc.Record(ctx, "10.0.0.1", event); err != nil {
+					t.Fatalf("Record() error = %v", err)
+				}
+			}
+
+			got, err := uc.Penalty(ctx, "10.0.0.1")
+			if err != nil {
+				t.Fatalf("Penalty() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Penalty() = %d, want %d", got, tt.want)
+			}
+
+			other, err := uc.Penalty(ctx, "10.0.0.2")
+			if err != nil || other != 0 {
+				t.Errorf("Penalty() for other client = %d, %v, want 0, nil", other, err)
+			}
+		})
+	}
+}
+
+func TestReputationUseCase_Errors(t *testing.T) {
+	storeErr := errors.New("store unavailable")
+	uc := NewReputationUseCase(&mockStore{err: storeErr}, time.Minute, 2, 8)
+
+	if err := uc.Record(context.Background(), "10.0.0.1", EventReplay); !errors.Is(err, storeErr) {
+		t.Errorf("Record() error = %v, want %v", err, storeErr)
+	}
+
+	if err := uc.Record(context.Background(), "10.0.0.1", Event("unknown")); err == nil {
+		t.Error("Record() error = nil, want error for unknown event")
+	}
+
+	if _, err := uc.Penalty(context.Background(), "10.0.0.1"); !errors.Is(err, storeErr) {
+		t.Errorf("Penalty() error = %v, want %v", err, storeErr)
+	}
+}
@@ -8,36 +8,133 @@ import (
 )
 
 type Config struct {
-	Server ServerConfig
-	Redis  RedisConfig
-	POW    POWConfig
-	Quotes QuotesConfig
-	Repo   struct {
+	Server     ServerConfig
+	Redis      RedisConfig
+	POW        POWConfig
+	Reputation ReputationConfig
+	Quotes     QuotesConfig
+	Repo       struct {
 		ConnectionString string `envconfig:"DBSTRING" required:"true"`
 		MigrationPath    string `envconfig:"MIGRATION_PATH" default:"/opt/migrations"`
 	}
 }
 
 type ServerConfig struct {
-	Port         string        `envconfig:"SERVER_PORT" default:"8080"`
+	Port string `envconfig:"SERVER_PORT" default:"8080"`
+	// ReadTimeout — срок первого сообщения, IdleTimeout — паузы между сообщениями
 	ReadTimeout  time.Duration `envconfig:"READ_TIMEOUT" default:"30s"`
 	WriteTimeout time.Duration `envconfig:"WRITE_TIMEOUT" default:"30s"`
 	IdleTimeout  time.Duration `envconfig:"IDLE_TIMEOUT" default:"60s"`
-	MaxConns     int           `envconfig:"MAX_CONNECTIONS" default:"100"`
-	RateLimit    int           `envconfig:"RATE_LIMIT" default:"10"`
-	RateWindow   time.Duration `envconfig:"RATE_WINDOW" default:"1m"`
+	// FrameTimeout — срок получения сообщения целиком после его первого байта.
+	// MinTransferRate (байт/с) после TransferGrace отсекает клиентов, которые
+	// передают или читают данные по байту
+	FrameTimeout    time.Duration `envconfig:"FRAME_TIMEOUT" default:"10s"`
+	MinTransferRate int           `envconfig:"MIN_TRANSFER_RATE" default:"256"`
+	TransferGrace   time.Duration `envconfig:"TRANSFER_GRACE" default:"2s"`
+	// MaxConnLifetime — предельное время жизни соединения, даже активного
+	MaxConnLifetime time.Duration `envconfig:"MAX_CONN_LIFETIME" default:"30m"`
+	MaxConns        int           `envconfig:"MAX_CONNECTIONS" default:"100"`
+	// MaxConnsPerIP — одновременных соединений с одного клиента, IP обрезается
+	// так же, как для лимитов (CLIENT_IPV4_PREFIX, CLIENT_IPV6_PREFIX)
+	MaxConnsPerIP int `envconfig:"MAX_CONNECTIONS_PER_IP" default:"10"`
+	// MaxFrameSize — предел тела сообщения в байтах для кадров v1 и v2
+	MaxFrameSize int `envconfig:"MAX_FRAME_SIZE" default:"65536"`
+	// MaxInflight — одновременных запросов с идентификатором на соединение
+	MaxInflight int           `envconfig:"MAX_INFLIGHT_REQUESTS" default:"4"`
+	RateLimit   int           `envconfig:"RATE_LIMIT" default:"10"`
+	RateWindow  time.Duration `envconfig:"RATE_WINDOW" default:"1m"`
+	// RateLimitBackend: memory — лимит на инстанс, redis — общий для всех инстансов
+	RateLimitBackend string `envconfig:"RATE_LIMIT_BACKEND" default:"memory"`
+	// Клиент определяется по IP, обрезанному до префикса указанной длины:
+	// 32 и 128 — отдельный адрес, 24 и 64 — сеть клиента
+	ClientIPv4Prefix int `envconfig:"CLIENT_IPV4_PREFIX" default:"32"`
+	ClientIPv6Prefix int `envconfig:"CLIENT_IPV6_PREFIX" default:"64"`
+	// StatsInterval — период журнала внутреннего состояния сервера, 0 — отключить
+	StatsInterval time.Duration `envconfig:"STATS_INTERVAL" default:"1m"`
 }
 
 type RedisConfig struct {
+	URL          string        `envconfig:"REDIS_URL" default:""`
 	Addr         string        `envconfig:"REDIS_ADDR" default:"localhost:6379"`
+	Username     string        `envconfig:"REDIS_USERNAME" default:""`
 	Password     string        `envconfig:"REDIS_PASSWORD" default:""`
 	DB           int           `envconfig:"REDIS_DB" default:"0"`
 	ChallengeTTL time.Duration `envconfig:"CHALLENGE_TTL" default:"20s"`
 	SpentTTL     time.Duration `envconfig:"SPENT_TTL" default:"2m"`
+
+	TLS                   bool   `envconfig:"REDIS_TLS" default:"false"`
+	TLSServerName         string `envconfig:"REDIS_TLS_SERVER_NAME" default:""`
+	TLSInsecureSkipVerify bool   `envconfig:"REDIS_TLS_INSECURE_SKIP_VERIFY" default:"false"`
+
+	SentinelMaster   string   `envconfig:"REDIS_SENTINEL_MASTER" default:""`
+	SentinelAddrs    []string `envconfig:"REDIS_SENTINEL_ADDRS" default:""`
+	SentinelPassword string   `envconfig:"REDIS_SENTINEL_PASSWORD" default:""`
+
+	ClusterAddrs []string `envconfig:"REDIS_CLUSTER_ADDRS" default:""`
 }
 
+const (
+	ChallengeModeStored    = "stored"
+	ChallengeModeStateless = "stateless"
+
+	StampFormatWisdom   = "wisdom"
+	StampFormatHashcash = "hashcash"
+)
+
 type POWConfig struct {
 	Difficulty int `envconfig:"POW_DIFFICULTY" default:"20"`
+	// Адаптивная сложность меняется в пределах [DifficultyMin, DifficultyMax],
+	// если любой из сигналов нагрузки превышает свой порог
+	Adaptive        bool          `envconfig:"POW_ADAPTIVE" default:"false"`
+	DifficultyMin   int           `envconfig:"POW_DIFFICULTY_MIN" default:"16"`
+	DifficultyMax   int           `envconfig:"POW_DIFFICULTY_MAX" default:"26"`
+	AdjustInterval  time.Duration `envconfig:"POW_ADJUST_INTERVAL" default:"10s"`
+	LoadConnections int           `envconfig:"POW_LOAD_CONNECTIONS" default:"80"`
+	LoadIssueRate   float64       `envconfig:"POW_LOAD_ISSUE_RATE" default:"50"`
+	LoadFailureRate float64       `envconfig:"POW_LOAD_FAILURE_RATE" default:"0.2"`
+	LoadLatency     time.Duration `envconfig:"POW_LOAD_LATENCY" default:"200ms"`
+	// MaxPendingChallenges — выданных, но ещё не решённых challenge на соединение
+	MaxPendingChallenges int `envconfig:"POW_MAX_PENDING_CHALLENGES" default:"8"`
+	// Algorithms — разрешённые алгоритмы, первый используется по умолчанию
+	Algorithms []string `envconfig:"POW_ALGORITHMS" default:"sha-256"`
+	// Argon2id: память в KiB, число проходов и потоков. Сложность задаётся отдельно,
+	// так как одно вычисление на порядки дороже sha-256
+	Argon2Memory        uint32 `envconfig:"POW_ARGON2_MEMORY" default:"8192"`
+	Argon2Time          uint32 `envconfig:"POW_ARGON2_TIME" default:"1"`
+	Argon2Threads       uint8  `envconfig:"POW_ARGON2_THREADS" default:"1"`
+	Argon2Difficulty    int    `envconfig:"POW_ARGON2_DIFFICULTY" default:"4"`
+	Argon2MaxConcurrent int    `envconfig:"POW_ARGON2_MAX_CONCURRENT" default:"4"`
+	// ChallengeMode: stored — challenge хранится в Redis, stateless — подписывается HMAC
+	ChallengeMode      string `envconfig:"POW_CHALLENGE_MODE" default:"stored"`
+	HMACSecret         string `envconfig:"POW_HMAC_SECRET" default:""`
+	HMACPreviousSecret string `envconfig:"POW_HMAC_PREVIOUS_SECRET" default:""`
+	// ChallengeBackend: redis или memory для одиночных инсталляций без Redis
+	ChallengeBackend       string        `envconfig:"POW_CHALLENGE_BACKEND" default:"redis"`
+	ChallengeMaxEntries    int           `envconfig:"POW_CHALLENGE_MAX_ENTRIES" default:"100000"`
+	ChallengeSweepInterval time.Duration `envconfig:"POW_CHALLENGE_SWEEP_INTERVAL" default:"10s"`
+	// StampFormat: wisdom — собственный заголовок, hashcash — марки Hashcash v1,
+	// которые можно выпустить стандартной утилитой для ресурса HashcashResource
+	StampFormat      string `envconfig:"POW_STAMP_FORMAT" default:"wisdom"`
+	HashcashResource string `envconfig:"POW_HASHCASH_RESOURCE" default:"wisdom-gate"`
+	// StampValidity отсчитывается от конца периода даты марки, StampMaxSpentTTL
+	// ограничивает, сколько помнится потраченная марка
+	StampValidity    time.Duration `envconfig:"POW_STAMP_VALIDITY" default:"5m"`
+	StampMaxSpentTTL time.Duration `envconfig:"POW_STAMP_MAX_SPENT_TTL" default:"1h"`
+}
+
+const (
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+)
+
+// ReputationConfig — надбавка к сложности для клиентов с нарушениями.
+// Каждые PenaltyStep очков счёта добавляют бит сложности, не более MaxPenalty.
+type ReputationConfig struct {
+	Backend     string        `envconfig:"REPUTATION_BACKEND" default:"memory"`
+	HalfLife    time.Duration `envconfig:"REPUTATION_HALF_LIFE" default:"10m"`
+	PenaltyStep float64       `envconfig:"REPUTATION_PENALTY_STEP" default:"2"`
+	MaxPenalty  int           `envconfig:"REPUTATION_MAX_PENALTY" default:"8"`
+	MaxClients  int           `envconfig:"REPUTATION_MAX_CLIENTS" default:"100000"`
 }
 
 type QuotesConfig struct {
@@ -59,6 +156,10 @@ func NewConfig() (*Config, error) {
 		return nil, fmt.Errorf("failed to parse pow config: %w", err)
 	}
 
+	if err := envconfig.Process("", &config.Reputation); err != nil {
+		return nil, fmt.Errorf("failed to parse reputation config: %w", err)
+	}
+
 	if err := envconfig.Process("", &config.Quotes); err != nil {
 		return nil, fmt.Errorf("failed to parse quotes config: %w", err)
 	}
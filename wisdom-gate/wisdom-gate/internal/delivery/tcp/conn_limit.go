@@ -0,0 +1,69 @@
+package tcp
+
+import (
+	"errors"
+	"sync"
+)
+
+var (
+	ErrServerBusy         = errors.New("server busy")
+	ErrTooManyConnections = errors.New("too many connections from client")
+)
+
+// ConnLimiter ограничивает число одновременных соединений: всего и от одного
+// клиента. Нулевой предел отключает соответствующее ограничение
+type ConnLimiter struct {
+	slots     chan struct{}
+	perClient int
+
+	mu      sync.Mutex
+	clients map[string]int
+}
+
+func NewConnLimiter(maxConns, maxPerClient int) *ConnLimiter {
+	l := &ConnLimiter{
+		perClient: maxPerClient,
+		clients:   make(map[string]int),
+	}
+	if maxConns > 0 {
+		l.slots = make(chan struct{}, maxConns)
+	}
+
+	return l
+}
+
+// Acquire занимает место под соединение клиента, не дожидаясь освобождения.
+// После успешного вызова соединение освобождается через Release
+func (l *ConnLimiter) Acquire(client string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.perClient > 0 && l.clients[client] >= l.perClient {
+		return ErrTooManyConnections
+	}
+
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		default:
+			return ErrServerBusy
+		}
+	}
+
+	l.clients[client]++
+
+	return nil
+}
+
+func (l *ConnLimiter) Release(client string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.clients[client]--; l.clients[client] <= 0 {
+		delete(l.clients, client)
+	}
+
+	if l.slots != nil {
+		<-l.slots
+	}
+}
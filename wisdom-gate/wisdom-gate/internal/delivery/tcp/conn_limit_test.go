@@ -0,0 +1,102 @@
+package tcp
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"wisdom-gate/internal/config"
+	"wisdom-gate/pkg/protocol"
+)
+
+func TestConnLimiter_Acquire(t *testing.T) {
+	tests := []struct {
+		name         string
+		maxConns     int
+		maxPerClient int
+		clients      []string
+		wantErr      []error
+	}{
+		{
+			name:     "global cap",
+			maxConns: 2,
+			clients:  []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"},
+			wantErr:  []error{nil, nil, ErrServerBusy},
+		},
+		{
+			name:         "per client cap",
+			maxConns:     10,
+			maxPerClient: 2,
+			clients:      []string{"10.0.0.1", "10.0.0.1", "10.0.0.1", "10.0.0.2"},
+			wantErr:      []error{nil, nil, ErrTooManyConnections, nil},
+		},
+		{
+			name:    "no limits",
+			clients: []string{"10.0.0.1", "10.0.0.1", "10.0.0.1"},
+			wantErr: []error{nil, nil, nil},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewConnLimiter(tt.maxConns, tt.maxPerClient)
+			for i, client := range tt.clients {
+				if err := l.Acquire(client); !errors.Is(err, tt.wantErr[i]) {
+					t.Errorf("Acquire(%s) #%d error = %v, want %v", client, i, err, tt.wantErr[i])
+				}
+			}
+		})
+	}
+}
+
+func TestConnLimiter_Release(t *testing.T) {
+	l := NewConnLimiter(1, 1)
+
+	if err := l.Acquire("10.0.0.1"); err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	if err := l.Acquire("10.0.0.2"); !errors.Is(err, ErrServerBusy) {
+		t.Fatalf("Acquire() over cap error = %v, want %v", err, ErrServerBusy)
+	}
+
+	l.Release("10.0.0.1")
+	if err := l.Acquire("10.0.0.2"); err != nil {
+		t.Errorf("Acquire() after Release error = %v", err)
+	}
+	if len(l.clients) != 1 {
+		t.Errorf("clients = %v, want only 10.0.0.2", l.clients)
+	}
+}
+
+func TestServer_Reject(t *testing.T) {
+	s := &Server{
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		errors: newErrorCatalogue(&config.Config{}),
+	}
+
+	clientSide, serverSide := tcpPipe(t)
+	defer clientSide.Close()
+
+	s.reject(serverSide, "127.0.0.1", ErrTooManyConnections)
+
+	// Клиент v2 понимает отказ, пришедший текстом v1
+	client := protocol.NewClientConn(clientSide, protocol.FramingV2, 4096)
+	msg, err := client.ReadMessage()
+	if err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+	if msg.Command != protocol.CmdERR {
+		t.Fatalf("reply = %+v, want ERR", msg)
+	}
+
+	resp := protocol.ParseErrorResponse(msg.Body)
+	if resp.Code != protocol.CodeBusy || !resp.Retryable || resp.RetryAfter != time.Second {
+		t.Errorf("ERR = %+v, want retryable %s with 1s hint", resp, protocol.CodeBusy)
+	}
+
+	if _, err := client.ReadMessage(); !errors.Is(err, io.EOF) {
+		t.Errorf("ReadMessage() after ERR error = %v, want %v", err, io.EOF)
+	}
+}
@@ -0,0 +1,196 @@
+package tcp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"wisdom-gate/pkg/protocol"
+)
+
+// ErrSlowClient — клиент передаёт или принимает данные медленнее допустимого
+var ErrSlowClient = errors.New("client too slow")
+
+// GuardOptions — защита от клиентов, которые держат соединение, передавая
+// или читая данные по байту. Нулевые значения отключают проверку
+type GuardOptions struct {
+	// FrameTimeout — срок получения сообщения целиком после его первого байта
+	FrameTimeout time.Duration
+	// MinRate — минимальная скорость приёма и отправки в байтах в секунду,
+	// проверяется по истечении Grace с начала сообщения или записи
+	MinRate int
+	Grace   time.Duration
+}
+
+var _ protocol.BuffersWriter = (*guardedConn)(nil)
+
+// guardedConn сокращает сроки, выставленные через SetReadDeadline и
+// SetWriteDeadline: начатое сообщение должно прийти за FrameTimeout, а данные
+// в обе стороны идти не медленнее MinRate. Выставленный срок остаётся внешним
+// пределом, а вызов SetReadDeadline означает ожидание следующего сообщения.
+// После нарушения чтение и запись сразу возвращают ErrSlowClient
+type guardedConn struct {
+	net.Conn
+	opts GuardOptions
+
+	mu            sync.Mutex
+	readDeadline  time.Time
+	writeDeadline time.Time
+	// frameStart — время первого байта текущего сообщения
+	frameStart time.Time
+	frameBytes int
+	slow       error
+}
+
+func newGuardedConn(conn net.Conn, opts GuardOptions) *guardedConn {
+	return &guardedConn{Conn: conn, opts: opts}
+}
+
+func (c *guardedConn) Read(p []byte) (int, error) {
+	c.mu.Lock()
+	slow := c.slow
+	c.mu.Unlock()
+	if slow != nil {
+		return 0, slow
+	}
+
+	n, err := c.Conn.Read(p)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Чтение прервано медленной записью
+	if c.slow != nil {
+		return n, c.slow
+	}
+
+	now := time.Now()
+	if err != nil {
+		// Сработал не внешний срок, а ограничение сообщения
+		if errors.Is(err, os.ErrDeadlineExceeded) && !c.frameStart.IsZero() &&
+			(c.readDeadline.IsZero() || now.Before(c.readDeadline)) {
+			c.slow = fmt.Errorf("%w: received %d bytes in %s", ErrSlowClient, c.frameBytes, now.Sub(c.frameStart).Round(time.Millisecond))
+			return n, c.slow
+		}
+		return n, err
+	}
+
+	if n > 0 {
+		if c.frameStart.IsZero() {
+			c.frameStart = now
+		}
+		c.frameBytes += n
+		_ = c.Conn.SetReadDeadline(c.frameDeadline())
+	}
+
+	return n, nil
+}
+
+// frameDeadline — ближайший из внешнего срока, конца FrameTimeout и момента,
+// когда принятых байт станет меньше, чем требует MinRate
+func (c *guardedConn) frameDeadline() time.Time {
+	deadline := c.readDeadline
+	if c.opts.FrameTimeout > 0 {
+		deadline = earliest(deadline, c.frameStart.Add(c.opts.FrameTimeout))
+	}
+	if c.opts.MinRate > 0 {
+		deadline = earliest(deadline, c.frameStart.Add(c.opts.Grace+transferTime(c.frameBytes, c.opts.MinRate)))
+	}
+	return deadline
+}
+
+func (c *guardedConn) Write(p []byte) (int, error) {
+	n, err := c.guardWrite(len(p), func() (int64, error) {
+		n, err := c.Conn.Write(p)
+		return int64(n), err
+	})
+	return int(n), err
+}
+
+// WriteBuffers передаёт буферы нижележащему соединению, чтобы кадр v2 ушёл одним
+// системным вызовом: через обёртку net.Buffers.WriteTo писал бы их по одному
+func (c *guardedConn) WriteBuffers(bufs *net.Buffers) (int64, error) {
+	size := 0
+	for _, b := range *bufs {
+		size += len(b)
+	}
+
+	return c.guardWrite(size, func() (int64, error) {
+		return bufs.WriteTo(c.Conn)
+	})
+}
+
+// guardWrite выставляет срок записи size байт с учётом MinRate и выполняет write
+func (c *guardedConn) guardWrite(size int, write func() (int64, error)) (int64, error) {
+	c.mu.Lock()
+	if c.slow != nil {
+		c.mu.Unlock()
+		return 0, c.slow
+	}
+	deadline := c.writeDeadline
+	if c.opts.MinRate > 0 {
+		deadline = earliest(deadline, time.Now().Add(c.opts.Grace+transferTime(size, c.opts.MinRate)))
+	}
+	_ = c.Conn.SetWriteDeadline(deadline)
+	c.mu.Unlock()
+
+	n, err := write()
+	if err == nil || !errors.Is(err, os.ErrDeadlineExceeded) {
+		return n, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.writeDeadline.IsZero() || time.Now().Before(c.writeDeadline) {
+		c.slow = fmt.Errorf("%w: sent %d of %d bytes", ErrSlowClient, n, size)
+		// Клиент не читает ответы: прерываем и чтение, чтобы обработчик закрыл соединение
+		_ = c.Conn.SetReadDeadline(time.Now())
+		return n, c.slow
+	}
+
+	return n, err
+}
+
+func (c *guardedConn) SetDeadline(t time.Time) error {
+	if err := c.SetReadDeadline(t); err != nil {
+		return err
+	}
+	return c.SetWriteDeadline(t)
+}
+
+func (c *guardedConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.readDeadline = t
+	c.frameStart = time.Time{}
+	c.frameBytes = 0
+
+	return c.Conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline запоминает внешний срок, он применяется к следующей записи
+func (c *guardedConn) SetWriteDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.writeDeadline = t
+
+	return nil
+}
+
+// earliest возвращает более ранний срок, нулевое время — без срока
+func earliest(a, b time.Time) time.Time {
+	if a.IsZero() || (!b.IsZero() && b.Before(a)) {
+		return b
+	}
+	return a
+}
+
+func transferTime(bytes, rate int) time.Duration {
+	return time.Duration(bytes) * time.Second / time.Duration(rate)
+}
@@ -0,0 +1,134 @@
+package tcp
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"wisdom-gate/pkg/protocol"
+)
+
+func TestGuardedConn_Read(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    GuardOptions
+		send    []string
+		pause   time.Duration
+		wantErr error
+	}{
+		{
+			name: "whole message",
+			opts: GuardOptions{FrameTimeout: time.Second, MinRate: 100, Grace: 50 * time.Millisecond},
+			send: []string{"REQ\n"},
+		},
+		{
+			name:    "trickle below min rate",
+			opts:    GuardOptions{FrameTimeout: 10 * time.Second, MinRate: 100, Grace: 50 * time.Millisecond},
+			send:    []string{"R", "E", "Q", "\n"},
+			pause:   100 * time.Millisecond,
+			wantErr: ErrSlowClient,
+		},
+		{
+			name:    "frame timeout",
+			opts:    GuardOptions{FrameTimeout: 100 * time.Millisecond},
+			send:    []string{"REQ"},
+			wantErr: ErrSlowClient,
+		},
+		{
+			name:    "idle is not slow",
+			opts:    GuardOptions{FrameTimeout: time.Second, MinRate: 100, Grace: 50 * time.Millisecond},
+			wantErr: os.ErrDeadlineExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientSide, serverSide := tcpPipe(t)
+			defer clientSide.Close()
+			defer serverSide.Close()
+
+			guarded := newGuardedConn(serverSide, tt.opts)
+			_ = guarded.SetReadDeadline(time.Now().Add(300 * time.Millisecond))
+
+			go func() {
+				for _, chunk := range tt.send {
+					if _, err := clientSide.Write([]byte(chunk)); err != nil {
+						return
+					}
+					time.Sleep(tt.pause)
+				}
+			}()
+
+			_, err := protocol.NewServerConn(guarded, 4096).ReadMessage()
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ReadMessage() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGuardedConn_SlowReader(t *testing.T) {
+	clientSide, serverSide := tcpPipe(t)
+	defer clientSide.Close()
+	defer serverSide.Close()
+
+	guarded := newGuardedConn(serverSide, GuardOptions{MinRate: 1 << 30, Grace: 50 * time.Millisecond})
+	_ = guarded.SetWriteDeadline(time.Now().Add(10 * time.Second))
+
+	// Клиент не читает: ответ застревает в буферах сокета
+	readErr := make(chan error, 1)
+	go func() {
+		_, err := guarded.Read(make([]byte, 1))
+		readErr <- err
+	}()
+
+	if _, err := guarded.Write(make([]byte, 64<<20)); !errors.Is(err, ErrSlowClient) {
+		t.Fatalf("Write() error = %v, want %v", err, ErrSlowClient)
+	}
+	if err := <-readErr; !errors.Is(err, ErrSlowClient) {
+		t.Errorf("Read() after slow write error = %v, want %v", err, ErrSlowClient)
+	}
+}
+
+func TestHandler_HandleConnection_SlowClient(t *testing.T) {
+	handler := newTestHandler(t, HandlerOptions{
+		IdleTimeout: time.Minute,
+		Guard:       GuardOptions{FrameTimeout: 100 * time.Millisecond},
+	})
+
+	clientSide, serverSide := tcpPipe(t)
+	defer clientSide.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		defer serverSide.Close()
+		done <- handler.HandleConnection(context.Background(), serverSide, "127.0.0.1")
+	}()
+
+	// Начало сообщения без конца строки
+	if _, err := clientSide.Write([]byte("REQ 6 |sha")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	if reason := CloseReason(<-done); reason != protocol.CloseReasonSlowClient {
+		t.Errorf("CloseReason() = %q, want %q", reason, protocol.CloseReasonSlowClient)
+	}
+}
+
+func TestGuardedConn_WriteFrame(t *testing.T) {
+	clientSide, serverSide := tcpPipe(t)
+	defer clientSide.Close()
+	defer serverSide.Close()
+
+	guarded := newGuardedConn(serverSide, GuardOptions{MinRate: 100, Grace: time.Second})
+	if err := protocol.WriteFrame(guarded, protocol.CmdQOT, []byte("quote")); err != nil {
+		t.Fatalf("WriteFrame() error = %v", err)
+	}
+
+	msg, err := protocol.NewClientConn(clientSide, protocol.FramingV2, 4096).ReadMessage()
+	if err != nil || msg.Command != protocol.CmdQOT || msg.Body != "quote" {
+		t.Errorf("ReadMessage() = %+v, %v, want QOT quote", msg, err)
+	}
+}
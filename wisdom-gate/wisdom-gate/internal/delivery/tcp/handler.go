@@ -1,41 +1,282 @@
 package tcp
 
 import (
-	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net"
+	"os"
+	"sync"
+	"syscall"
+	"time"
 
-	protocolUC "wisdom-gate/internal/application/protocol/usecase"
-	v1 "wisdom-gate/internal/delivery/tcp/v1"
+	"wisdom-gate/internal/delivery/tcp/middleware"
+	"wisdom-gate/pkg/protocol"
 )
 
+// HandlerOptions — ограничения одного соединения
+type HandlerOptions struct {
+	MaxFrameSize int
+	// MaxInflight — сколько запросов с идентификатором обрабатываются одновременно;
+	// при достижении предела чтение новых сообщений приостанавливается
+	MaxInflight int
+	// HandshakeTimeout — срок первого сообщения, IdleTimeout — паузы между сообщениями.
+	// MaxLifetime ограничивает жизнь соединения независимо от активности.
+	// Нулевые значения отключают ограничение
+	HandshakeTimeout time.Duration
+	IdleTimeout      time.Duration
+	MaxLifetime      time.Duration
+	WriteTimeout     time.Duration
+	Guard            GuardOptions
+	// MaxPendingChallenges — выданных, но ещё не решённых challenge на соединение
+	MaxPendingChallenges int
+}
+
+// CloseError сообщает, почему соединение закрыто
+type CloseError struct {
+	Reason string
+	Err    error
+}
+
+func (e *CloseError) Error() string {
+	if e.Err == nil {
+		return e.Reason
+	}
+	return e.Reason + ": " + e.Err.Error()
+}
+
+func (e *CloseError) Unwrap() error {
+	return e.Err
+}
+
+// CloseReason возвращает причину закрытия из ошибки HandleConnection
+func CloseReason(err error) string {
+	var closeErr *CloseError
+	if errors.As(err, &closeErr) {
+		return closeErr.Reason
+	}
+	return protocol.CloseReasonError
+}
+
 type Handler struct {
-	api    *v1.API
-	logger *slog.Logger
+	apis      map[int]API
+	handshake *Handshake
+	errors    *middleware.ErrorCatalogue
+	logger    *slog.Logger
+	opts      HandlerOptions
 }
 
-func NewHandler(api *v1.API, logger *slog.Logger) *Handler {
+func NewHandler(apis map[int]API, handshake *Handshake, errors *middleware.ErrorCatalogue, logger *slog.Logger, opts HandlerOptions) *Handler {
+	if opts.MaxInflight <= 0 {
+		opts.MaxInflight = 1
+	}
+
 	return &Handler{
-		api:    api,
-		logger: logger,
+		apis:      apis,
+		handshake: handshake,
+		errors:    errors,
+		logger:    logger,
+		opts:      opts,
 	}
 }
 
-func (h *Handler) HandleConnection(ctx context.Context, conn net.Conn, clientAddr string) error {
-	reader := bufio.NewReader(conn)
+func (h *Handler) HandleConnection(ctx context.Context, netConn net.Conn, clientAddr string) error {
+	// Сроки чтения и записи дальше выставляются через защищённое соединение
+	netConn = newGuardedConn(netConn, h.opts.Guard)
+
+	// Формат кадров (текст v1 или двоичный v2) определяется по первому байту,
+	// ответы уходят в том же формате
+	conn := protocol.NewServerConn(netConn, h.opts.MaxFrameSize)
+
+	// Клиент без HELLO работает по первой версии протокола
+	api := h.apis[protocol.ProtocolV1]
+
+	session := middleware.NewSession(h.opts.MaxPendingChallenges)
+	ctx = context.WithValue(ctx, middleware.SessionKey, session)
+	defer func() {
+		stats := session.Stats()
+		h.logger.Debug("Session closed", "client", clientAddr, "state", stats.State,
+			"challenges", stats.Challenges, "pending", stats.Pending, "failures", stats.Failures, "served", stats.Served)
+	}()
 
-	for {
-		msg, err := protocolUC.ReadMessage(reader)
+	var expires time.Time
+	if h.opts.MaxLifetime > 0 {
+		expires = time.Now().Add(h.opts.MaxLifetime)
+	}
+
+	// Остановка сервера прерывает ожидание следующего сообщения
+	stop := context.AfterFunc(ctx, func() { _ = netConn.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	// Запросы с идентификатором обрабатываются параллельно, ответы на них
+	// могут прийти в любом порядке. Соединение закрывается после их завершения
+	var (
+		inflight = make(chan struct{}, h.opts.MaxInflight)
+		wg       sync.WaitGroup
+	)
+	defer wg.Wait()
+
+	for first := true; ; first = false {
+		timeout := h.opts.IdleTimeout
+		if first {
+			timeout = h.opts.HandshakeTimeout
+		}
+		_ = netConn.SetReadDeadline(readDeadline(timeout, expires))
+		// Отмена до установки срока AfterFunc уже не прервёт чтение
+		if ctx.Err() != nil {
+			wg.Wait()
+			return h.disconnect(conn, protocol.CloseReasonShutdown, ctx.Err())
+		}
+
+		msg, err := conn.ReadMessage()
 		if err != nil {
-			return fmt.Errorf("failed to read message: %w", err)
+			wg.Wait()
+			return h.closeOnReadError(ctx, conn, expires, err)
+		}
+		h.setWriteDeadline(conn)
+
+		h.logger.Debug("Received message", "command", msg.Command, "id", msg.ID, "client", clientAddr, "framing", conn.Framing())
+
+		// HELLO допустим только первым сообщением, позже его отклонит API версии
+		if first && msg.Command == protocol.CmdHELLO {
+			info, err := h.negotiate(replyConn(conn, msg), msg)
+			if err != nil {
+				return &CloseError{Reason: protocol.CloseReasonProtocol, Err: fmt.Errorf("handshake failed: %w", err)}
+			}
+
+			api = h.apis[info.Version]
+			ctx = context.WithValue(ctx, middleware.VersionKey, info)
+			h.logger.Debug("Protocol negotiated", "client", clientAddr, "version", info.Version)
+			continue
 		}
 
-		h.logger.Debug("Received message", "command", msg.Command, "addr", clientAddr)
+		// PING только продлевает жизнь соединения и не проходит лимиты API
+		if msg.Command == protocol.CmdPING {
+			pong := &protocol.Message{Command: protocol.CmdPONG, Body: msg.Body}
+			if err := protocol.WriteMessage(replyConn(conn, msg), pong); err != nil {
+				return &CloseError{Reason: protocol.CloseReasonError, Err: fmt.Errorf("failed to send pong: %w", err)}
+			}
+			continue
+		}
 
-		if err := h.api.HandleMessage(ctx, conn, clientAddr, msg); err != nil {
-			h.logger.Error("Error handling message", "addr", clientAddr, "error", err)
+		if msg.ID == "" {
+			h.dispatch(ctx, api, conn, clientAddr, msg)
+			continue
 		}
+
+		inflight <- struct{}{}
+		wg.Add(1)
+		go func(ctx context.Context, api API, msg *protocol.Message) {
+			defer wg.Done()
+			defer func() { <-inflight }()
+
+			h.dispatch(ctx, api, replyConn(conn, msg), clientAddr, msg)
+		}(ctx, api, msg)
+	}
+}
+
+// closeOnReadError определяет причину закрытия и, если соединение закрывает
+// сервер, сообщает её клиенту
+func (h *Handler) closeOnReadError(ctx context.Context, conn *protocol.Conn, expires time.Time, err error) error {
+	err = fmt.Errorf("failed to read message: %w", err)
+
+	switch {
+	case errors.Is(err, ErrSlowClient):
+		// Медленному клиенту ничего не отправляем: запись тоже отклоняется
+		return &CloseError{Reason: protocol.CloseReasonSlowClient, Err: err}
+	case errors.Is(err, os.ErrDeadlineExceeded):
+		switch {
+		case ctx.Err() != nil:
+			return h.disconnect(conn, protocol.CloseReasonShutdown, err)
+		case !expires.IsZero() && !time.Now().Before(expires):
+			return h.disconnect(conn, protocol.CloseReasonLifetime, err)
+		default:
+			return h.disconnect(conn, protocol.CloseReasonIdle, err)
+		}
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, net.ErrClosed), errors.Is(err, syscall.ECONNRESET):
+		return &CloseError{Reason: protocol.CloseReasonClient, Err: err}
+	case h.errors.Code(err) == protocol.CodeBadFrame:
+		// Испорченный кадр не даёт читать дальше, но клиент узнаёт причину
+		h.setWriteDeadline(conn)
+		_ = h.errors.WriteError(conn, err)
+		return &CloseError{Reason: protocol.CloseReasonProtocol, Err: err}
+	default:
+		return &CloseError{Reason: protocol.CloseReasonError, Err: err}
+	}
+}
+
+// disconnect отправляет клиенту DISC с причиной закрытия
+func (h *Handler) disconnect(conn *protocol.Conn, reason string, err error) error {
+	h.setWriteDeadline(conn)
+	_ = conn.WriteMessage(&protocol.Message{Command: protocol.CmdDISC, Body: reason})
+
+	return &CloseError{Reason: reason, Err: err}
+}
+
+func (h *Handler) setWriteDeadline(conn net.Conn) {
+	if h.opts.WriteTimeout > 0 {
+		_ = conn.SetWriteDeadline(time.Now().Add(h.opts.WriteTimeout))
+	}
+}
+
+// readDeadline — ближайший из сроков ожидания и конца жизни соединения,
+// нулевое время снимает ограничение
+func readDeadline(timeout time.Duration, expires time.Time) time.Time {
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	if !expires.IsZero() && (deadline.IsZero() || expires.Before(deadline)) {
+		deadline = expires
+	}
+	return deadline
+}
+
+func (h *Handler) dispatch(ctx context.Context, api API, conn net.Conn, clientAddr string, msg *protocol.Message) {
+	if err := api.HandleMessage(ctx, conn, clientAddr, msg); err != nil {
+		h.logger.Error("Error handling message", "client", clientAddr, "id", msg.ID, "error", err)
+	}
+}
+
+// negotiate отвечает на HELLO сообщением VER, а при неудаче — ERR
+func (h *Handler) negotiate(conn net.Conn, msg *protocol.Message) (*protocol.VersionInfo, error) {
+	hello, err := protocol.ParseHello(msg.Body)
+	if err != nil {
+		err = fmt.Errorf("%w: %w", ErrInvalidHello, err)
+	}
+
+	var info *protocol.VersionInfo
+	if err == nil {
+		info, err = h.handshake.Negotiate(hello)
+	}
+
+	if err != nil {
+		_ = h.errors.WriteError(conn, err)
+		return nil, err
+	}
+
+	return info, protocol.WriteMessage(conn, &protocol.Message{Command: protocol.CmdVER, Body: info.String()})
+}
+
+// requestConn повторяет идентификатор запроса во всех ответах на него
+type requestConn struct {
+	*protocol.Conn
+	id string
+}
+
+func (c *requestConn) WriteMessage(msg *protocol.Message) error {
+	reply := *msg
+	reply.ID = c.id
+
+	return c.Conn.WriteMessage(&reply)
+}
+
+func replyConn(conn *protocol.Conn, msg *protocol.Message) net.Conn {
+	if msg.ID == "" {
+		return conn
 	}
+	return &requestConn{Conn: conn, id: msg.ID}
 }
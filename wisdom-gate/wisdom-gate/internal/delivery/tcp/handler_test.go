@@ -0,0 +1,170 @@
+package tcp
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"wisdom-gate/internal/config"
+	"wisdom-gate/pkg/protocol"
+)
+
+func newTestHandler(t *testing.T, opts HandlerOptions) *Handler {
+	t.Helper()
+
+	apis := map[int]API{
+		protocol.ProtocolV1: recordingAPI{version: "v1"},
+		protocol.ProtocolV2: recordingAPI{version: "v2"},
+	}
+	opts.MaxFrameSize = 4096
+
+	return NewHandler(apis, newTestHandshake(t), newErrorCatalogue(&config.Config{}), slog.New(slog.NewTextHandler(io.Discard, nil)), opts)
+}
+
+// tcpPipe — пара соединений через loopback: в отличие от net.Pipe запись
+// буферизуется, и встречные PING и DISC не блокируют друг друга
+func tcpPipe(t *testing.T) (net.Conn, net.Conn) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	defer ln.Close()
+
+	clientSide, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("net.Dial() error = %v", err)
+	}
+	serverSide, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("Accept() error = %v", err)
+	}
+
+	return clientSide, serverSide
+}
+
+func TestHandler_HandleConnection_Ping(t *testing.T) {
+	handler := newTestHandler(t, HandlerOptions{})
+
+	clientSide, serverSide := net.Pipe()
+	defer clientSide.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		defer serverSide.Close()
+		done <- handler.HandleConnection(context.Background(), serverSide, "127.0.0.1")
+	}()
+
+	client := protocol.NewClientConn(clientSide, protocol.FramingV1, 4096)
+	if err := client.WriteMessage(&protocol.Message{Command: protocol.CmdPING, Body: "42", ID: "p1"}); err != nil {
+		t.Fatalf("WriteMessage() error = %v", err)
+	}
+
+	got, err := client.ReadMessage()
+	if err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+	if want := (protocol.Message{Command: protocol.CmdPONG, Body: "42", ID: "p1"}); *got != want {
+		t.Errorf("reply = %+v, want %+v", got, want)
+	}
+
+	_ = clientSide.Close()
+	if reason := CloseReason(<-done); reason != protocol.CloseReasonClient {
+		t.Errorf("CloseReason() = %q, want %q", reason, protocol.CloseReasonClient)
+	}
+}
+
+func TestHandler_HandleConnection_Close(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       HandlerOptions
+		cancel     bool
+		pings      int
+		wantReason string
+	}{
+		{
+			name:       "silent client",
+			opts:       HandlerOptions{HandshakeTimeout: 50 * time.Millisecond, IdleTimeout: time.Minute},
+			wantReason: protocol.CloseReasonIdle,
+		},
+		{
+			name:       "idle after ping",
+			opts:       HandlerOptions{HandshakeTimeout: time.Minute, IdleTimeout: 50 * time.Millisecond},
+			pings:      1,
+			wantReason: protocol.CloseReasonIdle,
+		},
+		{
+			name:       "lifetime exceeded despite pings",
+			opts:       HandlerOptions{IdleTimeout: time.Minute, MaxLifetime: 150 * time.Millisecond},
+			pings:      -1,
+			wantReason: protocol.CloseReasonLifetime,
+		},
+		{
+			name:       "server shutdown",
+			opts:       HandlerOptions{IdleTimeout: time.Minute},
+			cancel:     true,
+			wantReason: protocol.CloseReasonShutdown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := newTestHandler(t, tt.opts)
+
+			clientSide, serverSide := tcpPipe(t)
+			defer clientSide.Close()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			done := make(chan error, 1)
+			go func() {
+				defer serverSide.Close()
+				done <- handler.HandleConnection(ctx, serverSide, "127.0.0.1")
+			}()
+
+			// Молчащему клиенту сервер отвечает текстом v1: формат ещё не определён
+			client := protocol.NewClientConn(clientSide, protocol.FramingV1, 4096)
+
+			// Отрицательное число — пинговать до закрытия соединения сервером
+			var msg *protocol.Message
+			for i := 0; tt.pings < 0 || i < tt.pings; i++ {
+				if err := client.WriteMessage(&protocol.Message{Command: protocol.CmdPING}); err != nil {
+					t.Fatalf("WriteMessage() error = %v", err)
+				}
+
+				reply, err := client.ReadMessage()
+				if err != nil {
+					t.Fatalf("ReadMessage() error = %v", err)
+				}
+				if reply.Command != protocol.CmdPONG {
+					msg = reply
+					break
+				}
+				time.Sleep(20 * time.Millisecond)
+			}
+
+			if tt.cancel {
+				cancel()
+			}
+
+			if msg == nil {
+				var err error
+				if msg, err = client.ReadMessage(); err != nil {
+					t.Fatalf("ReadMessage() error = %v", err)
+				}
+			}
+			if want := (protocol.Message{Command: protocol.CmdDISC, Body: tt.wantReason}); *msg != want {
+				t.Errorf("reply = %+v, want %+v", msg, want)
+			}
+
+			if reason := CloseReason(<-done); reason != tt.wantReason {
+				t.Errorf("CloseReason() = %q, want %q", reason, tt.wantReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package tcp
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	powUC "wisdom-gate/internal/application/pow/usecase"
+	"wisdom-gate/pkg/protocol"
+)
+
+var (
+	ErrInvalidHello      = errors.New("invalid hello")
+	ErrNoCommonVersion   = errors.New("no common protocol version")
+	ErrNoCommonAlgorithm = errors.New("no common algorithm")
+)
+
+// Handshake выбирает версию протокола и алгоритмы в ответ на HELLO
+type Handshake struct {
+	registry     *powUC.Registry
+	difficulty   *powUC.DifficultyController
+	versions     []int
+	maxFrameSize int
+}
+
+func NewHandshake(registry *powUC.Registry, difficulty *powUC.DifficultyController, versions []int, maxFrameSize int) *Handshake {
+	return &Handshake{
+		registry:     registry,
+		difficulty:   difficulty,
+		versions:     versions,
+		maxFrameSize: maxFrameSize,
+	}
+}
+
+// Negotiate выбирает старшую общую версию и разрешённые сервером алгоритмы
+// из предложенных клиентом в порядке предпочтения сервера
+func (h *Handshake) Negotiate(hello *protocol.Hello) (*protocol.VersionInfo, error) {
+	version := 0
+	for _, v := range hello.Versions {
+		if v > version && slices.Contains(h.versions, v) {
+			version = v
+		}
+	}
+	if version == 0 {
+		return nil, fmt.Errorf("%w: server supports %v", ErrNoCommonVersion, h.versions)
+	}
+
+	algorithms := h.registry.Enabled()
+	if len(hello.Algorithms) > 0 {
+		algorithms = slices.DeleteFunc(algorithms, func(name string) bool {
+			return !slices.Contains(hello.Algorithms, name)
+		})
+	}
+	if len(algorithms) == 0 {
+		return nil, fmt.Errorf("%w: server allows %v", ErrNoCommonAlgorithm, h.registry.Enabled())
+	}
+
+	return &protocol.VersionInfo{
+		Version:      version,
+		Algorithms:   algorithms,
+		Difficulty:   h.difficulty.Current(),
+		MaxFrameSize: h.maxFrameSize,
+	}, nil
+}
@@ -0,0 +1,230 @@
+package tcp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"reflect"
+	"testing"
+
+	powUC "wisdom-gate/internal/application/pow/usecase"
+	"wisdom-gate/internal/config"
+	"wisdom-gate/pkg/protocol"
+)
+
+func newTestHandshake(t *testing.T) *Handshake {
+	t.Helper()
+
+	registry, err := powUC.NewRegistry(protocol.AlgorithmSha256, protocol.AlgorithmBlake2b)
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+	difficulty := powUC.NewDifficultyController(20, 20, 20, powUC.LoadThresholds{})
+
+	return NewHandshake(registry, difficulty, []int{protocol.ProtocolV1, protocol.ProtocolV2}, 4096)
+}
+
+func TestHandshake_Negotiate(t *testing.T) {
+	tests := []struct {
+		name    string
+		hello   *protocol.Hello
+		want    *protocol.VersionInfo
+		wantErr error
+	}{
+		{
+			name:  "highest common version, all algorithms",
+			hello: &protocol.Hello{Versions: []int{1, 2, 3}},
+			want: &protocol.VersionInfo{
+				Version:      2,
+				Algorithms:   []string{protocol.AlgorithmSha256, protocol.AlgorithmBlake2b},
+				Difficulty:   20,
+				MaxFrameSize: 4096,
+			},
+		},
+		{
+			name:  "algorithms in server order",
+			hello: &protocol.Hello{Versions: []int{1}, Algorithms: []string{protocol.AlgorithmSha512, protocol.AlgorithmBlake2b}},
+			want: &protocol.VersionInfo{
+				Version:      1,
+				Algorithms:   []string{protocol.AlgorithmBlake2b},
+				Difficulty:   20,
+				MaxFrameSize: 4096,
+			},
+		},
+		{
+			name:    "no common version",
+			hello:   &protocol.Hello{Versions: []int{3}},
+			wantErr: ErrNoCommonVersion,
+		},
+		{
+			name:    "no common algorithm",
+			hello:   &protocol.Hello{Versions: []int{2}, Algorithms: []string{protocol.AlgorithmArgon2id}},
+			wantErr: ErrNoCommonAlgorithm,
+		},
+	}
+
+	h := newTestHandshake(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := h.Negotiate(tt.hello)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Negotiate() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Negotiate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// recordingAPI отвечает командой с номером своей версии
+type recordingAPI struct {
+	version string
+}
+
+func (a recordingAPI) HandleMessage(ctx context.Context, conn net.Conn, clientAddr string, msg *protocol.Message) error {
+	return protocol.WriteMessage(conn, &protocol.Message{Command: protocol.CmdQOT, Body: a.version})
+}
+
+func TestHandler_HandleConnection_Dispatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		messages []*protocol.Message
+		want     []*protocol.Message
+	}{
+		{
+			name:     "without hello",
+			messages: []*protocol.Message{{Command: protocol.CmdREQ}},
+			want:     []*protocol.Message{{Command: protocol.CmdQOT, Body: "v1"}},
+		},
+		{
+			name: "hello selects v2",
+			messages: []*protocol.Message{
+				{Command: protocol.CmdHELLO, Body: "versions=1,2"},
+				{Command: protocol.CmdREQ},
+			},
+			want: []*protocol.Message{
+				{Command: protocol.CmdVER, Body: "version=2;algorithms=sha-256,blake2b;difficulty=20;max_frame=4096"},
+				{Command: protocol.CmdQOT, Body: "v2"},
+			},
+		},
+		{
+			name: "repeated hello goes to api",
+			messages: []*protocol.Message{
+				{Command: protocol.CmdHELLO, Body: "versions=1"},
+				{Command: protocol.CmdHELLO, Body: "versions=2"},
+			},
+			want: []*protocol.Message{
+				{Command: protocol.CmdVER, Body: "version=1;algorithms=sha-256,blake2b;difficulty=20;max_frame=4096"},
+				{Command: protocol.CmdQOT, Body: "v1"},
+			},
+		},
+		{
+			name:     "unsupported version",
+			messages: []*protocol.Message{{Command: protocol.CmdHELLO, Body: "versions=9"}},
+			want:     []*protocol.Message{{Command: protocol.CmdERR, Body: "code=UNSUPPORTED;retryable=false;message=unsupported protocol version or algorithm"}},
+		},
+		{
+			name:     "malformed hello",
+			messages: []*protocol.Message{{Command: protocol.CmdHELLO, Body: "algorithms=sha-256"}},
+			want:     []*protocol.Message{{Command: protocol.CmdERR, Body: "code=BAD_REQUEST;retryable=false;message=bad request"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apis := map[int]API{
+				protocol.ProtocolV1: recordingAPI{version: "v1"},
+				protocol.ProtocolV2: recordingAPI{version: "v2"},
+			}
+			handler := NewHandler(apis, newTestHandshake(t), newErrorCatalogue(&config.Config{}), slog.New(slog.NewTextHandler(io.Discard, nil)), HandlerOptions{MaxFrameSize: 4096})
+
+			clientSide, serverSide := net.Pipe()
+			defer clientSide.Close()
+
+			done := make(chan error, 1)
+			go func() {
+				defer serverSide.Close()
+				done <- handler.HandleConnection(context.Background(), serverSide, "127.0.0.1")
+			}()
+
+			client := protocol.NewClientConn(clientSide, protocol.FramingV2, 4096)
+			for i, msg := range tt.messages {
+				if err := client.WriteMessage(msg); err != nil {
+					t.Fatalf("WriteMessage() error = %v", err)
+				}
+
+				got, err := client.ReadMessage()
+				if err != nil {
+					t.Fatalf("ReadMessage() error = %v", err)
+				}
+				if *got != *tt.want[i] {
+					t.Errorf("reply %d = %+v, want %+v", i, got, tt.want[i])
+				}
+			}
+
+			_ = clientSide.Close()
+			<-done
+		})
+	}
+}
+
+// blockingAPI отвечает на запрос с идентификатором "slow" только после release
+type blockingAPI struct {
+	release chan struct{}
+}
+
+func (a blockingAPI) HandleMessage(ctx context.Context, conn net.Conn, clientAddr string, msg *protocol.Message) error {
+	if msg.ID == "slow" {
+		<-a.release
+	}
+	return protocol.WriteMessage(conn, &protocol.Message{Command: protocol.CmdQOT, Body: msg.Body})
+}
+
+func TestHandler_HandleConnection_Pipelining(t *testing.T) {
+	api := blockingAPI{release: make(chan struct{})}
+	apis := map[int]API{protocol.ProtocolV1: api, protocol.ProtocolV2: api}
+	handler := NewHandler(apis, newTestHandshake(t), newErrorCatalogue(&config.Config{}), slog.New(slog.NewTextHandler(io.Discard, nil)), HandlerOptions{MaxFrameSize: 4096, MaxInflight: 2})
+
+	clientSide, serverSide := net.Pipe()
+	defer clientSide.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		defer serverSide.Close()
+		done <- handler.HandleConnection(context.Background(), serverSide, "127.0.0.1")
+	}()
+
+	client := protocol.NewClientConn(clientSide, protocol.FramingV2, 4096)
+	for _, msg := range []*protocol.Message{
+		{Command: protocol.CmdREQ, Body: "first", ID: "slow"},
+		{Command: protocol.CmdREQ, Body: "second", ID: "fast"},
+	} {
+		if err := client.WriteMessage(msg); err != nil {
+			t.Fatalf("WriteMessage() error = %v", err)
+		}
+	}
+
+	// Быстрый запрос не ждёт медленного
+	got, err := client.ReadMessage()
+	if err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+	if want := (protocol.Message{Command: protocol.CmdQOT, Body: "second", ID: "fast"}); *got != want {
+		t.Errorf("first reply = %+v, want %+v", got, want)
+	}
+
+	close(api.release)
+	got, err = client.ReadMessage()
+	if err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+	if want := (protocol.Message{Command: protocol.CmdQOT, Body: "first", ID: "slow"}); *got != want {
+		t.Errorf("second reply = %+v, want %+v", got, want)
+	}
+
+	_ = clientSide.Close()
+	<-done
+}
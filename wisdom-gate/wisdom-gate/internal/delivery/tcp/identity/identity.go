@@ -0,0 +1,71 @@
+package identity
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+)
+
+// Extractor сводит адрес соединения к идентификатору клиента: порт отбрасывается,
+// а IP при необходимости обрезается до префикса, чтобы лимиты и subject
+// относились к клиенту (или его сети), а не к отдельному соединению
+type Extractor struct {
+	ipv4Bits int
+	ipv6Bits int
+}
+
+func NewExtractor(ipv4Bits, ipv6Bits int) (*Extractor, error) {
+	if ipv4Bits < 1 || ipv4Bits > 32 {
+		return nil, fmt.Errorf("invalid ipv4 prefix length: %d", ipv4Bits)
+	}
+	if ipv6Bits < 1 || ipv6Bits > 128 {
+		return nil, fmt.Errorf("invalid ipv6 prefix length: %d", ipv6Bits)
+	}
+
+	return &Extractor{ipv4Bits: ipv4Bits, ipv6Bits: ipv6Bits}, nil
+}
+
+// FromAddr возвращает идентификатор для адреса соединения
+func (e *Extractor) FromAddr(addr net.Addr) string {
+	if tcpAddr, ok := addr.(*net.TCPAddr); ok {
+		if ip, ok := netip.AddrFromSlice(tcpAddr.IP); ok {
+			return e.fromIP(ip)
+		}
+	}
+
+	return e.FromString(addr.String())
+}
+
+// FromString принимает "IP:PORT" или просто IP. Строки, не являющиеся IP
+// (например, адреса unix-сокетов), возвращаются без изменений
+func (e *Extractor) FromString(addr string) string {
+	host := addr
+	if h, _, err := net.SplitHostPort(addr); err == nil {
+		host = h
+	}
+
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return addr
+	}
+
+	return e.fromIP(ip)
+}
+
+func (e *Extractor) fromIP(ip netip.Addr) string {
+	// Зона (fe80::1%eth0) не относится к идентичности клиента
+	ip = ip.Unmap().WithZone("")
+
+	bits := e.ipv6Bits
+	if ip.Is4() {
+		bits = e.ipv4Bits
+	}
+
+	if bits == ip.BitLen() {
+		return ip.String()
+	}
+
+	prefix, _ := ip.Prefix(bits)
+
+	return prefix.String()
+}
@@ -0,0 +1,60 @@
+package identity
+
+import (
+	"net"
+	"testing"
+)
+
+func TestExtractor_FromString(t *testing.T) {
+	tests := []struct {
+		name     string
+		ipv4Bits int
+		ipv6Bits int
+		addr     string
+		want     string
+	}{
+		{name: "ipv4 with port", ipv4Bits: 32, ipv6Bits: 128, addr: "192.0.2.10:54321", want: "192.0.2.10"},
+		{name: "ipv4 without port", ipv4Bits: 32, ipv6Bits: 128, addr: "192.0.2.10", want: "192.0.2.10"},
+		{name: "ipv4 /24", ipv4Bits: 24, ipv6Bits: 64, addr: "192.0.2.10:54321", want: "192.0.2.0/24"},
+		{name: "ipv6 full", ipv4Bits: 32, ipv6Bits: 128, addr: "[2001:db8::1]:54321", want: "2001:db8::1"},
+		{name: "ipv6 /64", ipv4Bits: 32, ipv6Bits: 64, addr: "[2001:db8:1:2:3:4:5:6]:54321", want: "2001:db8:1:2::/64"},
+		{name: "ipv4-mapped ipv6", ipv4Bits: 24, ipv6Bits: 64, addr: "[::ffff:192.0.2.10]:54321", want: "192.0.2.0/24"},
+		{name: "zone is dropped", ipv4Bits: 32, ipv6Bits: 128, addr: "[fe80::1%eth0]:54321", want: "fe80::1"},
+		{name: "not an ip", ipv4Bits: 32, ipv6Bits: 64, addr: "pipe", want: "pipe"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := NewExtractor(tt.ipv4Bits, tt.ipv6Bits)
+			if err != nil {
+				t.Fatalf("NewExtractor() error = %v", err)
+			}
+
+			if got := e.FromString(tt.addr); got != tt.want {
+				t.Errorf("FromString(%q) = %v, want %v", tt.addr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractor_FromAddr(t *testing.T) {
+	e, err := NewExtractor(24, 64)
+	if err != nil {
+		t.Fatalf("NewExtractor() error = %v", err)
+	}
+
+	// Соединения с разных портов одного клиента дают один идентификатор
+	first := e.FromAddr(&net.TCPAddr{IP: net.ParseIP("192.0.2.10"), Port: 40000})
+	second := e.FromAddr(&net.TCPAddr{IP: net.ParseIP("192.0.2.11"), Port: 40001})
+	if first != second || first != "192.0.2.0/24" {
+		t.Errorf("FromAddr() = %v and %v, want 192.0.2.0/24", first, second)
+	}
+}
+
+func TestNewExtractor_InvalidPrefix(t *testing.T) {
+	for _, bits := range [][2]int{{0, 64}, {33, 64}, {32, 0}, {32, 129}} {
+		if _, err := NewExtractor(bits[0], bits[1]); err == nil {
+			t.Errorf("NewExtractor(%d, %d) error = nil, want error", bits[0], bits[1])
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package tcp
+
+import (
+	"context"
+	"net"
+
+	"wisdom-gate/pkg/protocol"
+)
+
+// API обрабатывает сообщения одной версии протокола, см. v1.API и v2.API
+type API interface {
+	HandleMessage(ctx context.Context, conn net.Conn, clientAddr string, msg *protocol.Message) error
+}
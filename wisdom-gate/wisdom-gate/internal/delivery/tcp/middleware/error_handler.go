@@ -5,21 +5,17 @@ import (
 	"fmt"
 	"net"
 
-	"wisdom-gate/internal/application/protocol/consts"
-	protocolUC "wisdom-gate/internal/application/protocol/usecase"
+	"wisdom-gate/pkg/protocol"
 )
 
-func ErrorHandlerMiddleware() Middleware {
+// ErrorHandlerMiddleware отвечает на ошибку сообщением ERR с кодом из каталога
+// и возвращает исходную ошибку дальше, в журнал
+func ErrorHandlerMiddleware(catalogue *ErrorCatalogue) Middleware {
 	return func(next Handler) Handler {
-		return func(ctx context.Context, conn net.Conn, clientAddr string, msg *protocolUC.Message) error {
+		return func(ctx context.Context, conn net.Conn, clientAddr string, msg *protocol.Message) error {
 			err := next(ctx, conn, clientAddr, msg)
 			if err != nil {
-				errorMsg := &protocolUC.Message{
-					Command: consts.CmdERR,
-					Body:    fmt.Sprintf("ERROR: %s", err.Error()),
-				}
-
-				if writeErr := protocolUC.WriteMessage(conn, errorMsg); writeErr != nil {
+				if writeErr := catalogue.WriteError(conn, err); writeErr != nil {
 					return fmt.Errorf("failed to send error response: %w", writeErr)
 				}
 
@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"wisdom-gate/internal/adapters/memory"
+	"wisdom-gate/internal/adapters/redis"
+	powUC "wisdom-gate/internal/application/pow/usecase"
+	"wisdom-gate/pkg/protocol"
+)
+
+var (
+	ErrUnavailable       = errors.New("backend unavailable")
+	ErrUnknownCommand    = errors.New("unknown client command")
+	ErrMalformedSolution = errors.New("malformed solution")
+)
+
+// expiredErrors и invalidSolutionErrors — ошибки проверки решения по вине клиента,
+// по ним же начисляется репутация
+var (
+	expiredErrors         = []error{ErrChallengeExpired, powUC.ErrStampExpired, redis.ErrChallengeNotFound}
+	invalidSolutionErrors = []error{
+		ErrInsufficientWork,
+		ErrMalformedSolution,
+		powUC.ErrBadSignature,
+		powUC.ErrStampFromFuture,
+		powUC.ErrStampResourceMismatch,
+		protocol.ErrVersionMismatch,
+		protocol.ErrDifficultyMismatch,
+		protocol.ErrExpiresAtMismatch,
+		protocol.ErrSubjectMismatch,
+		protocol.ErrAlgorithmMismatch,
+		protocol.ErrNonceMismatch,
+	}
+)
+
+// errorEntry — то, что клиент узнаёт об ошибке: безопасный текст вместо err.Error()
+type errorEntry struct {
+	message   string
+	retryable bool
+}
+
+var errorEntries = map[protocol.ErrorCode]errorEntry{
+	protocol.CodePoWExpired:  {message: "challenge expired", retryable: true},
+	protocol.CodePoWReplay:   {message: "challenge already used", retryable: true},
+	protocol.CodePoWInvalid:  {message: "invalid proof of work"},
+	protocol.CodeRateLimited: {message: "rate limit exceeded", retryable: true},
+	protocol.CodeUnavailable: {message: "service temporarily unavailable", retryable: true},
+	protocol.CodeBusy:        {message: "server busy", retryable: true},
+	protocol.CodeBadFrame:    {message: "malformed frame"},
+	protocol.CodeBadRequest:  {message: "bad request"},
+	protocol.CodeUnsupported: {message: "unsupported protocol version or algorithm"},
+	protocol.CodeInternal:    {message: "internal error"},
+}
+
+type errorRule struct {
+	target error
+	code   protocol.ErrorCode
+}
+
+// ErrorCatalogue сопоставляет внутренние ошибки стабильным кодам ERR.
+// Правила проверяются по порядку через errors.Is, неизвестные ошибки
+// получают CodeInternal; подробности остаются только в журнале сервера
+type ErrorCatalogue struct {
+	rules      []errorRule
+	retryAfter map[protocol.ErrorCode]time.Duration
+}
+
+// NewErrorCatalogue создаёт каталог со встроенными правилами,
+// retryAfter задаёт подсказку клиенту для повторяемых кодов
+func NewErrorCatalogue(retryAfter map[protocol.ErrorCode]time.Duration) *ErrorCatalogue {
+	c := &ErrorCatalogue{retryAfter: retryAfter}
+
+	for _, target := range expiredErrors {
+		c.Register(target, protocol.CodePoWExpired)
+	}
+	c.Register(powUC.ErrChallengeSpent, protocol.CodePoWReplay)
+	for _, target := range invalidSolutionErrors {
+		c.Register(target, protocol.CodePoWInvalid)
+	}
+	c.Register(ErrRateLimitExceeded, protocol.CodeRateLimited)
+	c.Register(ErrTooManyChallenges, protocol.CodeRateLimited)
+	c.Register(ErrUnavailable, protocol.CodeUnavailable)
+	c.Register(memory.ErrStoreFull, protocol.CodeUnavailable)
+	for _, target := range []error{
+		protocol.ErrFrameTooLarge,
+		protocol.ErrBadFrameMagic,
+		protocol.ErrUnsupportedFrameVersion,
+		protocol.ErrInvalidCommand,
+	} {
+		c.Register(target, protocol.CodeBadFrame)
+	}
+	c.Register(ErrUnknownCommand, protocol.CodeBadRequest)
+	c.Register(ErrOutOfOrder, protocol.CodeBadRequest)
+	c.Register(powUC.ErrUnknownAlgorithm, protocol.CodeUnsupported)
+	c.Register(powUC.ErrAlgorithmNotAllowed, protocol.CodeUnsupported)
+
+	return c
+}
+
+// Register добавляет правило для ошибок уровня выше middleware, например рукопожатия
+func (c *ErrorCatalogue) Register(target error, code protocol.ErrorCode) {
+	c.rules = append(c.rules, errorRule{target: target, code: code})
+}
+
+// isAny сообщает, соответствует ли err хотя бы одной из targets
+func isAny(err error, targets []error) bool {
+	for _, target := range targets {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *ErrorCatalogue) Code(err error) protocol.ErrorCode {
+	for _, rule := range c.rules {
+		if errors.Is(err, rule.target) {
+			return rule.code
+		}
+	}
+
+	// Таймауты хранилищ и сети считаем временной недоступностью
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return protocol.CodeUnavailable
+	}
+
+	return protocol.CodeInternal
+}
+
+// Response возвращает тело ERR для ошибки
+func (c *ErrorCatalogue) Response(err error) *protocol.ErrorResponse {
+	code := c.Code(err)
+	entry := errorEntries[code]
+
+	resp := &protocol.ErrorResponse{
+		Code:      code,
+		Message:   entry.message,
+		Retryable: entry.retryable,
+	}
+	if entry.retryable {
+		resp.RetryAfter = c.retryAfter[code]
+	}
+
+	return resp
+}
+
+// WriteError отправляет клиенту ERR с кодом ошибки
+func (c *ErrorCatalogue) WriteError(conn net.Conn, err error) error {
+	return protocol.WriteMessage(conn, &protocol.Message{
+		Command: protocol.CmdERR,
+		Body:    c.Response(err).String(),
+	})
+}
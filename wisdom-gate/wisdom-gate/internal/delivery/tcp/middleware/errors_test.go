@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"wisdom-gate/internal/adapters/memory"
+	"wisdom-gate/internal/adapters/redis"
+	powUC "wisdom-gate/internal/application/pow/usecase"
+	"wisdom-gate/pkg/protocol"
+)
+
+func TestErrorCatalogue_Response(t *testing.T) {
+	catalogue := NewErrorCatalogue(map[protocol.ErrorCode]time.Duration{
+		protocol.CodeRateLimited: 6 * time.Second,
+	})
+
+	tests := []struct {
+		name       string
+		err        error
+		wantCode   protocol.ErrorCode
+		wantRetry  bool
+		wantAfter  time.Duration
+		wantHidden string
+	}{
+		{
+			name:      "expired challenge",
+			err:       ErrChallengeExpired,
+			wantCode:  protocol.CodePoWExpired,
+			wantRetry: true,
+		},
+		{
+			name:      "challenge gone from store",
+			err:       fmt.Errorf("failed to consume challenge: %w", redis.ErrChallengeNotFound),
+			wantCode:  protocol.CodePoWExpired,
+			wantRetry: true,
+		},
+		{
+			name:      "replay",
+			err:       powUC.ErrChallengeSpent,
+			wantCode:  protocol.CodePoWReplay,
+			wantRetry: true,
+		},
+		{
+			name:     "subject mismatch",
+			err:      protocol.ErrSubjectMismatch,
+			wantCode: protocol.CodePoWInvalid,
+		},
+		{
+			name:      "rate limited with hint",
+			err:       ErrRateLimitExceeded,
+			wantCode:  protocol.CodeRateLimited,
+			wantRetry: true,
+			wantAfter: 6 * time.Second,
+		},
+		{
+			name:       "backend failure is not leaked",
+			err:        fmt.Errorf("%w: failed to get quote: %w", ErrUnavailable, errors.New("pgx: connection refused to 10.0.0.5")),
+			wantCode:   protocol.CodeUnavailable,
+			wantRetry:  true,
+			wantHidden: "10.0.0.5",
+		},
+		{
+			name:      "memory store full",
+			err:       fmt.Errorf("failed to store challenge: %w", memory.ErrStoreFull),
+			wantCode:  protocol.CodeUnavailable,
+			wantRetry: true,
+		},
+		{
+			name:     "oversized frame",
+			err:      fmt.Errorf("%w: 70000 > 65536", protocol.ErrFrameTooLarge),
+			wantCode: protocol.CodeBadFrame,
+		},
+		{
+			name:     "unknown command",
+			err:      fmt.Errorf("%w: FOO", ErrUnknownCommand),
+			wantCode: protocol.CodeBadRequest,
+		},
+		{
+			name:     "solution from another connection",
+			err:      fmt.Errorf("%w: challenge was not issued on this connection", ErrOutOfOrder),
+			wantCode: protocol.CodeBadRequest,
+		},
+		{
+			name:      "challenge farming",
+			err:       ErrTooManyChallenges,
+			wantCode:  protocol.CodeRateLimited,
+			wantRetry: true,
+			wantAfter: 6 * time.Second,
+		},
+		{
+			name:     "algorithm not allowed",
+			err:      powUC.ErrAlgorithmNotAllowed,
+			wantCode: protocol.CodeUnsupported,
+		},
+		{
+			name:       "unknown error",
+			err:        errors.New("runtime error: index out of range"),
+			wantCode:   protocol.CodeInternal,
+			wantHidden: "index out of range",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := catalogue.Response(tt.err)
+			if got.Code != tt.wantCode {
+				t.Errorf("Code = %v, want %v", got.Code, tt.wantCode)
+			}
+			if got.Retryable != tt.wantRetry {
+				t.Errorf("Retryable = %v, want %v", got.Retryable, tt.wantRetry)
+			}
+			if got.RetryAfter != tt.wantAfter {
+				t.Errorf("RetryAfter = %v, want %v", got.RetryAfter, tt.wantAfter)
+			}
+			if got.Message == "" {
+				t.Error("Message is empty")
+			}
+			if tt.wantHidden != "" && strings.Contains(got.String(), tt.wantHidden) {
+				t.Errorf("Response() = %q leaks %q", got.String(), tt.wantHidden)
+			}
+		})
+	}
+}
+
+func TestErrorCatalogue_Register(t *testing.T) {
+	errCustom := errors.New("custom")
+
+	catalogue := NewErrorCatalogue(nil)
+	if got := catalogue.Code(errCustom); got != protocol.CodeInternal {
+		t.Errorf("Code() before Register = %v, want %v", got, protocol.CodeInternal)
+	}
+
+	catalogue.Register(errCustom, protocol.CodeUnsupported)
+	if got := catalogue.Code(fmt.Errorf("wrapped: %w", errCustom)); got != protocol.CodeUnsupported {
+		t.Errorf("Code() after Register = %v, want %v", got, protocol.CodeUnsupported)
+	}
+}
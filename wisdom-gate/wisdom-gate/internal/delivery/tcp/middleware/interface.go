@@ -0,0 +1,8 @@
+package middleware
+
+import "context"
+
+// Limiter решает, можно ли обработать ещё одно сообщение клиента с ключом key
+type Limiter interface {
+	Allow(ctx context.Context, key string) (bool, error)
+}
@@ -5,18 +5,23 @@ import (
 	"net"
 	"time"
 
-	protocolUC "wisdom-gate/internal/application/protocol/usecase"
+	powUC "wisdom-gate/internal/application/pow/usecase"
+	"wisdom-gate/pkg/protocol"
 )
 
 type Middleware func(next Handler) Handler
 
-type Handler func(ctx context.Context, conn net.Conn, clientAddr string, msg *protocolUC.Message) error
+type Handler func(ctx context.Context, conn net.Conn, clientAddr string, msg *protocol.Message) error
 
 type ContextKey string
 
 const (
 	ClientAddrKey ContextKey = "client_addr"
 	VerifiedKey   ContextKey = "verified"
+	// VersionKey — *protocol.VersionInfo, согласованная через HELLO/VER
+	VersionKey ContextKey = "version"
+	// SessionKey — *Session соединения, см. SessionMiddleware
+	SessionKey ContextKey = "session"
 )
 
 func Chain(middlewares ...Middleware) Middleware {
@@ -29,10 +34,11 @@ func Chain(middlewares ...Middleware) Middleware {
 	}
 }
 
+// TimeoutMiddleware ограничивает время записи ответа. Сроком чтения управляет
+// обработчик соединения: запросы с идентификатором идут параллельно с чтением
 func TimeoutMiddleware(timeout time.Duration) Middleware {
 	return func(next Handler) Handler {
-		return func(ctx context.Context, conn net.Conn, clientAddr string, msg *protocolUC.Message) error {
-			func() { _ = conn.SetReadDeadline(time.Now().Add(timeout)) }()
+		return func(ctx context.Context, conn net.Conn, clientAddr string, msg *protocol.Message) error {
 			func() { _ = conn.SetWriteDeadline(time.Now().Add(timeout)) }()
 
 			return next(ctx, conn, clientAddr, msg)
@@ -42,10 +48,23 @@ func TimeoutMiddleware(timeout time.Duration) Middleware {
 
 func LoggingMiddleware() Middleware {
 	return func(next Handler) Handler {
-		return func(ctx context.Context, conn net.Conn, clientAddr string, msg *protocolUC.Message) error {
+		return func(ctx context.Context, conn net.Conn, clientAddr string, msg *protocol.Message) error {
 			ctx = context.WithValue(ctx, ClientAddrKey, clientAddr)
 
 			return next(ctx, conn, clientAddr, msg)
 		}
 	}
 }
+
+// LatencyMiddleware сообщает контроллеру сложности время обработки сообщений
+func LatencyMiddleware(difficulty *powUC.DifficultyController) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, conn net.Conn, clientAddr string, msg *protocol.Message) error {
+			start := time.Now()
+			err := next(ctx, conn, clientAddr, msg)
+			difficulty.ObserveLatency(time.Since(start))
+
+			return err
+		}
+	}
+}
@@ -2,52 +2,71 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log/slog"
 	"net"
+	"strings"
 	"time"
 
-	"wisdom-gate/internal/adapters/redis"
 	powUC "wisdom-gate/internal/application/pow/usecase"
-	"wisdom-gate/internal/application/protocol/consts"
-	protocolUC "wisdom-gate/internal/application/protocol/usecase"
+	reputationUC "wisdom-gate/internal/application/reputation/usecase"
 	"wisdom-gate/internal/config"
+	"wisdom-gate/pkg/protocol"
 )
 
-func PoWChallengeMiddleware(redisClient redis.ClientInterface, cfg *config.Config) Middleware {
+var (
+	ErrChallengeExpired = errors.New("challenge expired")
+	ErrInsufficientWork = errors.New("insufficient proof of work")
+)
+
+func PoWChallengeMiddleware(
+	challengeStore powUC.ChallengeStoreInterface,
+	registry *powUC.Registry,
+	difficulty *powUC.DifficultyController,
+	reputation *reputationUC.ReputationUseCase,
+	cfg *config.Config,
+) Middleware {
 	return func(next Handler) Handler {
-		return func(ctx context.Context, conn net.Conn, clientAddr string, msg *protocolUC.Message) error {
-			if msg.Command != consts.CmdREQ {
+		return func(ctx context.Context, conn net.Conn, clientAddr string, msg *protocol.Message) error {
+			if msg.Command != protocol.CmdREQ {
 				return next(ctx, conn, clientAddr, msg)
 			}
 
-			nonce, err := powUC.GenerateNonce()
+			// В теле REQ клиент может перечислить поддерживаемые алгоритмы
+			var supported []string
+			if msg.Body != "" {
+				supported = strings.Split(msg.Body, protocol.AlgorithmSeparator)
+			}
+
+			algorithm, err := registry.Select(supported)
 			if err != nil {
-				return fmt.Errorf("failed to generate nonce: %w", err)
+				return err
 			}
 
+			penalty := reputationPenalty(ctx, reputation, clientAddr)
 			expiresAt := time.Now().Add(cfg.Redis.ChallengeTTL).Unix()
-			header := &protocolUC.HashcashHeader{
-				Version:    1,
-				Difficulty: cfg.POW.Difficulty,
+			header := &protocol.HashcashHeader{
+				Version:    protocol.HeaderVersionEncoded,
+				Difficulty: difficultyFor(cfg, algorithm, difficulty.Current()+penalty),
 				ExpiresAt:  expiresAt,
 				Subject:    clientAddr,
-				Algorithm:  consts.AlgorithmSha256,
-				Nonce:      nonce,
+				Algorithm:  algorithm,
 			}
 
-			token := nonce
-			challengeStr := header.String()
-			if err := redisClient.StoreChallenge(ctx, token, challengeStr, cfg.Redis.ChallengeTTL); err != nil {
-				return fmt.Errorf("failed to store challenge: %w", err)
+			if err := challengeStore.Issue(ctx, header); err != nil {
+				return fmt.Errorf("%w: failed to issue challenge: %w", ErrUnavailable, err)
+			}
+			difficulty.ObserveIssue()
+			if session, ok := SessionFromContext(ctx); ok {
+				session.ChallengeIssued(header.Nonce, time.Unix(header.ExpiresAt, 0), penalty)
 			}
 
-			challengeMsg := &protocolUC.Message{
-				Command: consts.CmdCHL,
-				Body:    challengeStr,
+			challengeMsg := &protocol.Message{
+				Command: protocol.CmdCHL,
+				Body:    header.String(),
 			}
 
-			if err := protocolUC.WriteMessage(conn, challengeMsg); err != nil {
+			if err := protocol.WriteMessage(conn, challengeMsg); err != nil {
 				return fmt.Errorf("failed to send challenge: %w", err)
 			}
 
@@ -56,61 +75,153 @@ func PoWChallengeMiddleware(redisClient redis.ClientInterface, cfg *config.Confi
 	}
 }
 
-func PoWVerificationMiddleware(redisClient redis.ClientInterface, powVerifier powUC.VerifierInterface, cfg *config.Config, logger *slog.Logger) Middleware {
+func PoWVerificationMiddleware(
+	challengeStore powUC.ChallengeStoreInterface,
+	powVerifier powUC.VerifierInterface,
+	difficulty *powUC.DifficultyController,
+	reputation *reputationUC.ReputationUseCase,
+	cfg *config.Config,
+) Middleware {
 	return func(next Handler) Handler {
-		return func(ctx context.Context, conn net.Conn, clientAddr string, msg *protocolUC.Message) error {
-			if msg.Command != consts.CmdREQ {
+		return func(ctx context.Context, conn net.Conn, clientAddr string, msg *protocol.Message) error {
+			if msg.Command != protocol.CmdRES {
 				return next(ctx, conn, clientAddr, msg)
 			}
 
-			header, err := protocolUC.ParseHashcashHeader(msg.Body)
+			err := verifySolution(ctx, challengeStore, powVerifier, difficulty, cfg, clientAddr, msg.Body)
+			observeVerification(ctx, difficulty, reputation, clientAddr, err)
 			if err != nil {
-				return fmt.Errorf("invalid header format: %w", err)
+				return err
 			}
 
-			if header.IsExpired() {
-				return fmt.Errorf("challenge expired")
-			}
+			return serveVerified(ctx, next, conn, clientAddr, msg)
+		}
+	}
+}
 
-			if !header.ValidateSubject(clientAddr) {
-				return fmt.Errorf("subject mismatch")
-			}
+func verifySolution(
+	ctx context.Context,
+	challengeStore powUC.ChallengeStoreInterface,
+	powVerifier powUC.VerifierInterface,
+	difficulty *powUC.DifficultyController,
+	cfg *config.Config,
+	clientAddr string,
+	solution string,
+) error {
+	header, err := protocol.ParseHashcashHeader(solution)
+	if err != nil {
+		return fmt.Errorf("%w: invalid header format: %w", ErrMalformedSolution, err)
+	}
 
-			if header.Difficulty != cfg.POW.Difficulty {
-				return fmt.Errorf("difficulty mismatch")
-			}
+	if header.IsExpired() {
+		return ErrChallengeExpired
+	}
 
-			token := header.Nonce
-			_, err = redisClient.GetChallenge(ctx, token)
-			if err != nil {
-				return fmt.Errorf("challenge not found")
-			}
+	if !header.ValidateSubject(clientAddr) {
+		return protocol.ErrSubjectMismatch
+	}
 
-			spent, err := redisClient.MarkChallengeSpent(ctx, token, cfg.Redis.SpentTTL)
-			if err != nil {
-				return fmt.Errorf("failed to check replay: %w", err)
-			}
+	// Решение принимается только на соединении, получившем challenge
+	if session, ok := SessionFromContext(ctx); ok {
+		if err := session.Redeem(header.Nonce); err != nil {
+			return err
+		}
+	}
 
-			if !spent {
-				return fmt.Errorf("challenge already used")
-			}
+	// Сложность могла измениться после выдачи challenge: подлинность заголовка
+	// гарантирует хранилище, здесь отсекаем только решения проще нижней границы
+	if headerBits(header) < difficultyFor(cfg, header.Algorithm, difficulty.Min()) {
+		return fmt.Errorf("%w: below minimum", protocol.ErrDifficultyMismatch)
+	}
 
-			valid, err := powVerifier.VerifySolution(msg.Body, header.Difficulty)
-			if err != nil {
-				return fmt.Errorf("verification failed: %w", err)
-			}
+	if err := challengeStore.Redeem(ctx, header); err != nil {
+		return redeemError(err)
+	}
 
-			if !valid {
-				return fmt.Errorf("insufficient proof of work")
-			}
+	valid, err := powVerifier.VerifySolution(solution, header.Algorithm, header.Version, header.Difficulty)
+	if err != nil {
+		return fmt.Errorf("verification failed: %w", err)
+	}
 
-			if err := redisClient.DeleteChallenge(ctx, token); err != nil {
-				logger.Warn("Failed to delete challenge from Redis", "token", token, "error", err)
-			}
+	if !valid {
+		return ErrInsufficientWork
+	}
 
-			ctx = context.WithValue(ctx, VerifiedKey, true)
+	return nil
+}
 
-			return next(ctx, conn, clientAddr, msg)
-		}
+// reputationPenalty — надбавка к сложности за репутацию клиента.
+// При недоступном хранилище репутации работаем без надбавки
+func reputationPenalty(ctx context.Context, reputation *reputationUC.ReputationUseCase, clientAddr string) int {
+	penalty, err := reputation.Penalty(ctx, clientAddr)
+	if err != nil {
+		return 0
+	}
+
+	return penalty
+}
+
+// difficultyFor переводит уровень контроллера в сложность для алгоритма:
+// у memory-hard алгоритмов своя шкала, сдвигаемая вместе с уровнем
+func difficultyFor(cfg *config.Config, algorithm string, level int) int {
+	if powUC.IsMemoryHard(algorithm) {
+		return max(cfg.POW.Argon2Difficulty+level-cfg.POW.Difficulty, 0)
+	}
+
+	return level
+}
+
+// headerBits возвращает сложность заголовка в битах независимо от версии
+func headerBits(header *protocol.HashcashHeader) int {
+	if header.Version == protocol.HeaderVersionHex {
+		return header.Difficulty * 4
+	}
+
+	return header.Difficulty
+}
+
+// redeemError относит к недоступности сервиса ошибки погашения не по вине клиента:
+// сбой или переполнение хранилища должны дойти до клиента как UNAVAILABLE
+func redeemError(err error) error {
+	if _, clientFault := verificationEvent(err); clientFault {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrUnavailable, err)
+}
+
+// observeVerification учитывает итог проверки в адаптивной сложности и репутации.
+// Сбои на стороне сервера, например недоступное хранилище, клиенту не засчитываются
+func observeVerification(
+	ctx context.Context,
+	difficulty *powUC.DifficultyController,
+	reputation *reputationUC.ReputationUseCase,
+	clientAddr string,
+	err error,
+) {
+	if err == nil {
+		difficulty.ObserveVerification(true)
+		return
+	}
+
+	event, ok := verificationEvent(err)
+	if !ok {
+		return
+	}
+	difficulty.ObserveVerification(false)
+	_ = reputation.Record(ctx, clientAddr, event)
+}
+
+// verificationEvent классифицирует неудачную проверку для репутации;
+// ok = false — ошибка не по вине клиента
+func verificationEvent(err error) (event reputationUC.Event, ok bool) {
+	switch {
+	case isAny(err, expiredErrors):
+		return reputationUC.EventExpiredChallenge, true
+	case errors.Is(err, powUC.ErrChallengeSpent):
+		return reputationUC.EventReplay, true
+	case isAny(err, invalidSolutionErrors), errors.Is(err, ErrOutOfOrder):
+		return reputationUC.EventFailedVerification, true
+	default:
+		return "", false
 	}
 }
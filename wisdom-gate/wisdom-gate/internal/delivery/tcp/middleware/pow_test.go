@@ -1,21 +1,23 @@
 package middleware
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
-	"log/slog"
+	"errors"
 	"net"
-	"os"
 	"strings"
 	"testing"
 	"time"
 
+	"wisdom-gate/internal/adapters/memory"
 	"wisdom-gate/internal/adapters/redis"
 	powUC "wisdom-gate/internal/application/pow/usecase"
-	"wisdom-gate/internal/application/protocol/consts"
-	"wisdom-gate/internal/application/protocol/usecase"
+	reputationUC "wisdom-gate/internal/application/reputation/usecase"
 	"wisdom-gate/internal/config"
+	"wisdom-gate/pkg/protocol"
 )
 
 func TestPoWChallengeMiddleware(t *testing.T) {
@@ -30,9 +32,14 @@ func TestPoWChallengeMiddleware(t *testing.T) {
 		},
 	}
 
-	middleware := PoWChallengeMiddleware(mockRedis, cfg)
+	registry, err := powUC.NewRegistry(protocol.AlgorithmSha256)
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+
+	middleware := PoWChallengeMiddleware(powUC.NewStoredChallengeStore(mockRedis, cfg.Redis.ChallengeTTL, cfg.Redis.SpentTTL), registry, newTestDifficulty(cfg), newTestReputation(), cfg)
 
-	nextHandler := func(ctx context.Context, conn net.Conn, clientAddr string, msg *usecase.Message) error {
+	nextHandler := func(ctx context.Context, conn net.Conn, clientAddr string, msg *protocol.Message) error {
 		return nil
 	}
 
@@ -40,12 +47,12 @@ func TestPoWChallengeMiddleware(t *testing.T) {
 
 	conn := &mockConn{}
 
-	reqMsg := &usecase.Message{
-		Command: consts.CmdREQ,
+	reqMsg := &protocol.Message{
+		Command: protocol.CmdREQ,
 		Body:    "",
 	}
 
-	err := handler(context.Background(), conn, "127.0.0.1:8080", reqMsg)
+	err = handler(context.Background(), conn, "127.0.0.1:8080", reqMsg)
 	if err != nil {
 		t.Errorf("PoWChallengeMiddleware() error = %v", err)
 	}
@@ -61,11 +68,7 @@ func TestPoWChallengeMiddleware(t *testing.T) {
 func TestPoWVerificationMiddleware(t *testing.T) {
 	mockRedis := redis.NewMockRedisClient()
 
-	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-		Level: slog.LevelInfo,
-	}))
-
-	verifier := powUC.NewVerifier()
+	verifier := newTestVerifier(t)
 
 	cfg := &config.Config{
 		Redis: config.RedisConfig{
@@ -76,9 +79,10 @@ func TestPoWVerificationMiddleware(t *testing.T) {
 		},
 	}
 
-	middleware := PoWVerificationMiddleware(mockRedis, verifier, cfg, logger)
+	challengeStore := powUC.NewStoredChallengeStore(mockRedis, time.Minute, cfg.Redis.SpentTTL)
+	middleware := PoWVerificationMiddleware(challengeStore, verifier, newTestDifficulty(cfg), newTestReputation(), cfg)
 
-	nextHandler := func(ctx context.Context, conn net.Conn, clientAddr string, msg *usecase.Message) error {
+	nextHandler := func(ctx context.Context, conn net.Conn, clientAddr string, msg *protocol.Message) error {
 		return nil
 	}
 
@@ -86,10 +90,11 @@ func TestPoWVerificationMiddleware(t *testing.T) {
 
 	conn := &mockConn{}
 
-	challenge := &usecase.HashcashHeader{
+	expiresAt := time.Now().Add(time.Minute).Unix()
+	challenge := &protocol.HashcashHeader{
 		Version:    1,
 		Difficulty: 1,
-		ExpiresAt:  time.Now().Add(time.Minute).Unix(),
+		ExpiresAt:  expiresAt,
 		Subject:    "127.0.0.1:8080",
 		Algorithm:  "sha-256",
 		Nonce:      "test-nonce",
@@ -106,10 +111,10 @@ func TestPoWVerificationMiddleware(t *testing.T) {
 	// Нужно найти counter который даст hash с нужным количеством нулей
 	var validCounter int64
 	for counter := int64(0); counter < 1000; counter++ {
-		solution := &usecase.HashcashHeader{
+		solution := &protocol.HashcashHeader{
 			Version:    1,
 			Difficulty: 1,
-			ExpiresAt:  time.Now().Add(time.Minute).Unix(),
+			ExpiresAt:  expiresAt,
 			Subject:    "127.0.0.1:8080",
 			Algorithm:  "sha-256",
 			Nonce:      "test-nonce",
@@ -125,10 +130,10 @@ func TestPoWVerificationMiddleware(t *testing.T) {
 		}
 	}
 
-	solution := &usecase.HashcashHeader{
+	solution := &protocol.HashcashHeader{
 		Version:    1,
 		Difficulty: 1,
-		ExpiresAt:  time.Now().Add(time.Minute).Unix(),
+		ExpiresAt:  expiresAt,
 		Subject:    "127.0.0.1:8080",
 		Algorithm:  "sha-256",
 		Nonce:      "test-nonce",
@@ -138,7 +143,7 @@ func TestPoWVerificationMiddleware(t *testing.T) {
 	solutionStr := solution.String()
 
 	// Тестируем RES команду
-	resMsg := &usecase.Message{
+	resMsg := &protocol.Message{
 		Command: "RES",
 		Body:    solutionStr,
 	}
@@ -149,6 +154,259 @@ func TestPoWVerificationMiddleware(t *testing.T) {
 	}
 }
 
+func TestPoWVerificationMiddleware_Tampering(t *testing.T) {
+	cfg := &config.Config{
+		Redis: config.RedisConfig{
+			SpentTTL: 2 * time.Minute,
+		},
+		POW: config.POWConfig{
+			Difficulty: 1,
+		},
+	}
+
+	expiresAt := time.Now().Add(time.Minute).Unix()
+	challenge := protocol.HashcashHeader{
+		Version:    1,
+		Difficulty: 1,
+		ExpiresAt:  expiresAt,
+		Subject:    "127.0.0.1:8080",
+		Algorithm:  "sha-256",
+		Nonce:      "test-nonce",
+	}
+
+	tests := []struct {
+		name    string
+		tamper  func(h *protocol.HashcashHeader)
+		wantErr error
+	}{
+		{
+			name:    "untouched solution",
+			tamper:  func(h *protocol.HashcashHeader) {},
+			wantErr: nil,
+		},
+		{
+			name:    "extended expiresAt",
+			tamper:  func(h *protocol.HashcashHeader) { h.ExpiresAt += 3600 },
+			wantErr: protocol.ErrExpiresAtMismatch,
+		},
+		{
+			name:    "changed version",
+			tamper:  func(h *protocol.HashcashHeader) { h.Version = protocol.HeaderVersionBits },
+			wantErr: protocol.ErrVersionMismatch,
+		},
+		{
+			name:    "changed algorithm",
+			tamper:  func(h *protocol.HashcashHeader) { h.Algorithm = protocol.AlgorithmSha512 },
+			wantErr: protocol.ErrAlgorithmMismatch,
+		},
+		{
+			name:    "changed subject",
+			tamper:  func(h *protocol.HashcashHeader) { h.Subject = "10.0.0.1:9999" },
+			wantErr: protocol.ErrSubjectMismatch,
+		},
+		{
+			name:    "changed difficulty",
+			tamper:  func(h *protocol.HashcashHeader) { h.Difficulty = 0 },
+			wantErr: protocol.ErrDifficultyMismatch,
+		},
+		{
+			name:    "unknown nonce",
+			tamper:  func(h *protocol.HashcashHeader) { h.Nonce = "other-nonce" },
+			wantErr: redis.ErrChallengeNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRedis := redis.NewMockRedisClient()
+			if err := mockRedis.StoreChallenge(context.Background(), challenge.Nonce, challenge.String(), time.Minute); err != nil {
+				t.Fatalf("Failed to store challenge: %v", err)
+			}
+
+			solution := challenge
+			tt.tamper(&solution)
+			solution.Counter = solveForTest(t, &solution)
+
+			challengeStore := powUC.NewStoredChallengeStore(mockRedis, time.Minute, cfg.Redis.SpentTTL)
+			handler := PoWVerificationMiddleware(challengeStore, newTestVerifier(t), newTestDifficulty(cfg), newTestReputation(), cfg)(
+				func(ctx context.Context, conn net.Conn, clientAddr string, msg *protocol.Message) error {
+					return nil
+				},
+			)
+
+			resMsg := &protocol.Message{
+				Command: protocol.CmdRES,
+				Body:    solution.String(),
+			}
+
+			err := handler(context.Background(), &mockConn{}, "127.0.0.1:8080", resMsg)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("PoWVerificationMiddleware() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// solveForTest подбирает counter, удовлетворяющий сложности заголовка
+func solveForTest(t *testing.T, header *protocol.HashcashHeader) int64 {
+	t.Helper()
+
+	verifier := newTestVerifier(t)
+	candidate := *header
+	for counter := int64(1); counter < 100000; counter++ {
+		candidate.Counter = counter
+		valid, err := verifier.VerifySolution(candidate.String(), candidate.Algorithm, candidate.Version, candidate.Difficulty)
+		if err != nil {
+			t.Fatalf("VerifySolution() error = %v", err)
+		}
+		if valid {
+			return counter
+		}
+	}
+
+	t.Fatal("failed to solve challenge")
+	return 0
+}
+
+func TestPoWVerificationMiddleware_DifficultyChangedAfterIssue(t *testing.T) {
+	cfg := &config.Config{
+		Redis: config.RedisConfig{
+			ChallengeTTL: time.Minute,
+			SpentTTL:     2 * time.Minute,
+		},
+		POW: config.POWConfig{
+			Difficulty: 6,
+		},
+	}
+
+	registry, err := powUC.NewRegistry(protocol.AlgorithmSha256)
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+
+	mockRedis := redis.NewMockRedisClient()
+	challengeStore := powUC.NewStoredChallengeStore(mockRedis, cfg.Redis.ChallengeTTL, cfg.Redis.SpentTTL)
+	difficulty := powUC.NewDifficultyController(6, 4, 8, powUC.LoadThresholds{ActiveConns: 10})
+
+	nextHandler := func(ctx context.Context, conn net.Conn, clientAddr string, msg *protocol.Message) error {
+		return nil
+	}
+
+	conn := &mockConn{}
+	challengeHandler := PoWChallengeMiddleware(challengeStore, registry, difficulty, newTestReputation(), cfg)(nextHandler)
+	reqMsg := &protocol.Message{Command: protocol.CmdREQ}
+	if err := challengeHandler(context.Background(), conn, "127.0.0.1:8080", reqMsg); err != nil {
+		t.Fatalf("PoWChallengeMiddleware() error = %v", err)
+	}
+
+	challengeMsg, err := protocol.ReadMessage(bufio.NewReader(bytes.NewReader(conn.writtenData[0])))
+	if err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+	challenge, err := protocol.ParseHashcashHeader(challengeMsg.Body)
+	if err != nil {
+		t.Fatalf("ParseHashcashHeader() error = %v", err)
+	}
+	if challenge.Difficulty != 6 {
+		t.Fatalf("challenge difficulty = %d, want 6", challenge.Difficulty)
+	}
+
+	// Нагрузка спала, сложность снижена до выдачи решения
+	difficulty.Evaluate(time.Now().Add(time.Second))
+	if difficulty.Current() != 5 {
+		t.Fatalf("DifficultyController.Current() = %d, want 5", difficulty.Current())
+	}
+
+	solution := *challenge
+	solution.Counter = solveForTest(t, &solution)
+
+	verifyHandler := PoWVerificationMiddleware(challengeStore, newTestVerifier(t), difficulty, newTestReputation(), cfg)(nextHandler)
+	resMsg := &protocol.Message{Command: protocol.CmdRES, Body: solution.String()}
+	if err := verifyHandler(context.Background(), conn, "127.0.0.1:8080", resMsg); err != nil {
+		t.Errorf("PoWVerificationMiddleware() error = %v", err)
+	}
+}
+
+func TestPoWChallengeMiddleware_ReputationPenalty(t *testing.T) {
+	cfg := &config.Config{
+		Redis: config.RedisConfig{
+			ChallengeTTL: time.Minute,
+			SpentTTL:     2 * time.Minute,
+		},
+		POW: config.POWConfig{
+			Difficulty: 4,
+		},
+	}
+
+	registry, err := powUC.NewRegistry(protocol.AlgorithmSha256)
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+
+	reputation := newTestReputation()
+	challengeStore := powUC.NewStoredChallengeStore(redis.NewMockRedisClient(), cfg.Redis.ChallengeTTL, cfg.Redis.SpentTTL)
+	difficulty := newTestDifficulty(cfg)
+	nextHandler := func(ctx context.Context, conn net.Conn, clientAddr string, msg *protocol.Message) error {
+		return nil
+	}
+
+	verifyHandler := PoWVerificationMiddleware(challengeStore, newTestVerifier(t), difficulty, reputation, cfg)(nextHandler)
+	challengeHandler := PoWChallengeMiddleware(challengeStore, registry, difficulty, reputation, cfg)(nextHandler)
+
+	challengeDifficulty := func(clientAddr string) int {
+		conn := &mockConn{}
+		if err := challengeHandler(context.Background(), conn, clientAddr, &protocol.Message{Command: protocol.CmdREQ}); err != nil {
+			t.Fatalf("PoWChallengeMiddleware() error = %v", err)
+		}
+
+		msg, err := protocol.ReadMessage(bufio.NewReader(bytes.NewReader(conn.writtenData[0])))
+		if err != nil {
+			t.Fatalf("ReadMessage() error = %v", err)
+		}
+		header, err := protocol.ParseHashcashHeader(msg.Body)
+		if err != nil {
+			t.Fatalf("ParseHashcashHeader() error = %v", err)
+		}
+
+		return header.Difficulty
+	}
+
+	// Пять заведомо неверных решений от одного клиента
+	for range 5 {
+		resMsg := &protocol.Message{Command: protocol.CmdRES, Body: "garbage"}
+		if err := verifyHandler(context.Background(), &mockConn{}, "10.0.0.1", resMsg); err == nil {
+			t.Fatal("PoWVerificationMiddleware() error = nil, want error")
+		}
+	}
+
+	if got := challengeDifficulty("10.0.0.1"); got != 6 {
+		t.Errorf("abusive client difficulty = %d, want 6", got)
+	}
+
+	if got := challengeDifficulty("10.0.0.2"); got != 4 {
+		t.Errorf("well-behaved client difficulty = %d, want 4", got)
+	}
+}
+
+func newTestReputation() *reputationUC.ReputationUseCase {
+	return reputationUC.NewReputationUseCase(memory.NewReputationStore(100), time.Hour, 2, 8)
+}
+
+func newTestDifficulty(cfg *config.Config) *powUC.DifficultyController {
+	return powUC.NewDifficultyController(cfg.POW.Difficulty, cfg.POW.Difficulty, cfg.POW.Difficulty, powUC.LoadThresholds{})
+}
+
+func newTestVerifier(t *testing.T) powUC.VerifierInterface {
+	t.Helper()
+
+	registry, err := powUC.NewRegistry(protocol.AlgorithmSha256, protocol.AlgorithmSha512)
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+
+	return powUC.NewVerifier(registry)
+}
+
 // mockConn - мок для net.Conn
 type mockConn struct {
 	writtenData [][]byte
@@ -165,3 +423,44 @@ func (m *mockConn) RemoteAddr() net.Addr               { return nil }
 func (m *mockConn) SetDeadline(t time.Time) error      { return nil }
 func (m *mockConn) SetReadDeadline(t time.Time) error  { return nil }
 func (m *mockConn) SetWriteDeadline(t time.Time) error { return nil }
+
+// failingChallengeStore имитирует недоступное хранилище challenge
+type failingChallengeStore struct{}
+
+func (failingChallengeStore) Issue(ctx context.Context, header *protocol.HashcashHeader) error {
+	return nil
+}
+
+func (failingChallengeStore) Redeem(ctx context.Context, solution *protocol.HashcashHeader) error {
+	return errors.New("connection refused")
+}
+
+func TestPoWVerificationMiddleware_StoreFailure(t *testing.T) {
+	cfg := &config.Config{POW: config.POWConfig{Difficulty: 4}}
+	store := memory.NewReputationStore(100)
+	reputation := reputationUC.NewReputationUseCase(store, time.Hour, 2, 8)
+
+	header := &protocol.HashcashHeader{
+		Version:    protocol.HeaderVersionEncoded,
+		Difficulty: 4,
+		ExpiresAt:  time.Now().Add(time.Minute).Unix(),
+		Subject:    "127.0.0.1",
+		Algorithm:  protocol.AlgorithmSha256,
+		Nonce:      "nonce",
+	}
+
+	handler := PoWVerificationMiddleware(failingChallengeStore{}, newTestVerifier(t), newTestDifficulty(cfg), reputation, cfg)(
+		func(ctx context.Context, conn net.Conn, clientAddr string, msg *protocol.Message) error {
+			return nil
+		})
+
+	err := handler(context.Background(), &mockConn{}, "127.0.0.1", &protocol.Message{Command: protocol.CmdRES, Body: header.String()})
+	if code := NewErrorCatalogue(nil).Code(err); code != protocol.CodeUnavailable {
+		t.Errorf("error code = %s, want %s (error %v)", code, protocol.CodeUnavailable, err)
+	}
+
+	// Сбой хранилища клиенту в вину не ставится
+	if score, _ := store.Score(context.Background(), "127.0.0.1", time.Hour); score != 0 {
+		t.Errorf("reputation score = %v, want 0", score)
+	}
+}
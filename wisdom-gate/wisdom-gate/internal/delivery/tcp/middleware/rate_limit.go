@@ -2,60 +2,179 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"hash/fnv"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	protocolUC "wisdom-gate/internal/application/protocol/usecase"
+	reputationUC "wisdom-gate/internal/application/reputation/usecase"
+	"wisdom-gate/pkg/protocol"
 )
 
+var ErrRateLimitExceeded = errors.New("rate limit exceeded")
+
+// rateLimiterShards — число шардов: ключи распределяются по ним хешем,
+// чтобы параллельные соединения реже конкурировали за одну блокировку
+const rateLimiterShards = 32
+
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+type rateLimiterShard struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+// RateLimiterStats — сводка состояния лимитера
+type RateLimiterStats struct {
+	Keys    int
+	Evicted uint64
+}
+
+// RateLimiter — token bucket в памяти процесса, каждый инстанс считает запросы отдельно.
+// Ведро ёмкостью limit пополняется на limit токенов за window; ключи, не
+// использовавшиеся дольше window (ведро к этому времени снова полное),
+// периодически удаляются, так что память ограничена активными клиентами.
 type RateLimiter struct {
-	requests map[string][]time.Time
-	mutex    sync.RWMutex
-	limit    int
-	window   time.Duration
+	shards  [rateLimiterShards]rateLimiterShard
+	limit   float64
+	rate    float64 // токенов в секунду
+	window  time.Duration
+	evicted atomic.Uint64
+	now     func() time.Time
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
 }
 
-func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
-	return &RateLimiter{
-		requests: make(map[string][]time.Time),
-		limit:    limit,
-		window:   window,
+func NewRateLimiter(limit int, window time.Duration) (*RateLimiter, error) {
+	if window <= 0 {
+		return nil, fmt.Errorf("rate window must be positive, got %s", window)
+	}
+
+	rl := &RateLimiter{
+		limit:  float64(limit),
+		rate:   float64(limit) / window.Seconds(),
+		window: window,
+		now:    time.Now,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
 	}
+	for i := range rl.shards {
+		rl.shards[i].buckets = make(map[string]*bucket)
+	}
+
+	go rl.janitor()
+
+	return rl, nil
 }
 
 func (rl *RateLimiter) IsAllowed(clientAddr string) bool {
-	rl.mutex.Lock()
-	defer rl.mutex.Unlock()
+	shard := rl.shard(clientAddr)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
 
-	now := time.Now()
-	cutoff := now.Add(-rl.window)
-
-	if requests, exists := rl.requests[clientAddr]; exists {
-		var validRequests []time.Time
-		for _, reqTime := range requests {
-			if reqTime.After(cutoff) {
-				validRequests = append(validRequests, reqTime)
-			}
-		}
-		rl.requests[clientAddr] = validRequests
+	now := rl.now()
+	b, exists := shard.buckets[clientAddr]
+	if !exists {
+		b = &bucket{tokens: rl.limit, lastSeen: now}
+		shard.buckets[clientAddr] = b
 	}
 
-	requests := rl.requests[clientAddr]
-	if len(requests) >= rl.limit {
+	b.tokens = min(rl.limit, b.tokens+now.Sub(b.lastSeen).Seconds()*rl.rate)
+	b.lastSeen = now
+
+	if b.tokens < 1 {
 		return false
 	}
 
-	rl.requests[clientAddr] = append(requests, now)
+	b.tokens--
 	return true
 }
 
-func RateLimitMiddleware(limiter *RateLimiter) Middleware {
+func (rl *RateLimiter) Allow(ctx context.Context, key string) (bool, error) {
+	return rl.IsAllowed(key), nil
+}
+
+// Stats возвращает число отслеживаемых ключей и общее число вытесненных
+func (rl *RateLimiter) Stats() RateLimiterStats {
+	stats := RateLimiterStats{Evicted: rl.evicted.Load()}
+	for i := range rl.shards {
+		shard := &rl.shards[i]
+		shard.mu.Lock()
+		stats.Keys += len(shard.buckets)
+		shard.mu.Unlock()
+	}
+
+	return stats
+}
+
+// Close останавливает фоновую очистку, повторный вызов безопасен
+func (rl *RateLimiter) Close() error {
+	rl.closeOnce.Do(func() {
+		close(rl.stop)
+		<-rl.done
+	})
+
+	return nil
+}
+
+func (rl *RateLimiter) shard(key string) *rateLimiterShard {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+
+	return &rl.shards[h.Sum32()%rateLimiterShards]
+}
+
+// evictIdle удаляет ключи, простаивавшие не меньше окна: их ведро уже полное,
+// и новая запись для того же ключа будет неотличима от удалённой
+func (rl *RateLimiter) evictIdle() {
+	now := rl.now()
+	for i := range rl.shards {
+		shard := &rl.shards[i]
+		shard.mu.Lock()
+		for key, b := range shard.buckets {
+			if now.Sub(b.lastSeen) >= rl.window {
+				delete(shard.buckets, key)
+				rl.evicted.Add(1)
+			}
+		}
+		shard.mu.Unlock()
+	}
+}
+
+func (rl *RateLimiter) janitor() {
+	defer close(rl.done)
+
+	ticker := time.NewTicker(rl.window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-rl.stop:
+			return
+		case <-ticker.C:
+			rl.evictIdle()
+		}
+	}
+}
+
+func RateLimitMiddleware(limiter Limiter, reputation *reputationUC.ReputationUseCase) Middleware {
 	return func(next Handler) Handler {
-		return func(ctx context.Context, conn net.Conn, clientAddr string, msg *protocolUC.Message) error {
-			if !limiter.IsAllowed(clientAddr) {
-				return fmt.Errorf("rate limit exceeded")
+		return func(ctx context.Context, conn net.Conn, clientAddr string, msg *protocol.Message) error {
+			allowed, err := limiter.Allow(ctx, clientAddr)
+			// Недоступность общего хранилища не должна останавливать сервис:
+			// от перегрузки в этом случае защищает PoW
+			if err == nil && !allowed {
+				// Репутация best-effort: сбой хранилища не должен влиять на ответ
+				_ = reputation.Record(ctx, clientAddr, reputationUC.EventRateLimited)
+				return ErrRateLimitExceeded
 			}
 			return next(ctx, conn, clientAddr, msg)
 		}
@@ -0,0 +1,154 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"wisdom-gate/pkg/protocol"
+)
+
+type stubLimiter struct {
+	allowed bool
+	err     error
+}
+
+func (l *stubLimiter) Allow(ctx context.Context, key string) (bool, error) {
+	return l.allowed, l.err
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		limiter  Limiter
+		wantErr  error
+		wantNext bool
+	}{
+		{
+			name:     "allowed",
+			limiter:  &stubLimiter{allowed: true},
+			wantNext: true,
+		},
+		{
+			name:    "limited",
+			limiter: &stubLimiter{allowed: false},
+			wantErr: ErrRateLimitExceeded,
+		},
+		{
+			name:     "limiter unavailable fails open",
+			limiter:  &stubLimiter{err: errors.New("connection refused")},
+			wantNext: true,
+		},
+		{
+			name:     "in-memory limiter",
+			limiter:  newTestRateLimiter(t, 1, time.Minute),
+			wantNext: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			next := func(ctx context.Context, conn net.Conn, clientAddr string, msg *protocol.Message) error {
+				called = true
+				return nil
+			}
+
+			handler := RateLimitMiddleware(tt.limiter, newTestReputation())(next)
+			err := handler(context.Background(), &mockConn{}, "127.0.0.1:12345", &protocol.Message{Command: protocol.CmdREQ})
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("RateLimitMiddleware() error = %v, want %v", err, tt.wantErr)
+			}
+			if called != tt.wantNext {
+				t.Errorf("next called = %v, want %v", called, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestRateLimiter_Allow(t *testing.T) {
+	limiter := newTestRateLimiter(t, 2, time.Minute)
+	ctx := context.Background()
+
+	for i, want := range []bool{true, true, false} {
+		allowed, err := limiter.Allow(ctx, "127.0.0.1")
+		if err != nil {
+			t.Fatalf("Allow() error = %v", err)
+		}
+		if allowed != want {
+			t.Errorf("Allow() call %d = %v, want %v", i+1, allowed, want)
+		}
+	}
+
+	if allowed, _ := limiter.Allow(ctx, "127.0.0.2"); !allowed {
+		t.Error("Allow() for another client = false, want true")
+	}
+}
+
+func TestRateLimiter_Refill(t *testing.T) {
+	limiter := newTestRateLimiter(t, 2, time.Minute)
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	limiter.IsAllowed("127.0.0.1")
+	limiter.IsAllowed("127.0.0.1")
+	if limiter.IsAllowed("127.0.0.1") {
+		t.Fatal("IsAllowed() with empty bucket = true, want false")
+	}
+
+	// За половину окна восстанавливается половина ёмкости — один токен
+	now = now.Add(30 * time.Second)
+	if !limiter.IsAllowed("127.0.0.1") {
+		t.Error("IsAllowed() after refill = false, want true")
+	}
+	if limiter.IsAllowed("127.0.0.1") {
+		t.Error("IsAllowed() after spending refilled token = true, want false")
+	}
+}
+
+func TestRateLimiter_EvictIdle(t *testing.T) {
+	limiter := newTestRateLimiter(t, 2, time.Minute)
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	for _, key := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+		limiter.IsAllowed(key)
+	}
+
+	now = now.Add(30 * time.Second)
+	limiter.IsAllowed("10.0.0.1")
+
+	now = now.Add(30 * time.Second)
+	limiter.evictIdle()
+
+	stats := limiter.Stats()
+	if stats.Keys != 1 {
+		t.Errorf("Stats().Keys = %d, want 1", stats.Keys)
+	}
+	if stats.Evicted != 2 {
+		t.Errorf("Stats().Evicted = %d, want 2", stats.Evicted)
+	}
+}
+
+func newTestRateLimiter(t *testing.T, limit int, window time.Duration) *RateLimiter {
+	t.Helper()
+
+	limiter, err := NewRateLimiter(limit, window)
+	if err != nil {
+		t.Fatalf("NewRateLimiter() error = %v", err)
+	}
+	t.Cleanup(func() { _ = limiter.Close() })
+
+	return limiter
+}
+
+func TestNewRateLimiter_InvalidWindow(t *testing.T) {
+	for _, window := range []time.Duration{0, -time.Second} {
+		if _, err := NewRateLimiter(1, window); err == nil {
+			t.Errorf("NewRateLimiter(1, %s) error = nil, want error", window)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"wisdom-gate/pkg/protocol"
+)
+
+var (
+	ErrOutOfOrder        = errors.New("command out of order")
+	ErrTooManyChallenges = errors.New("too many pending challenges")
+)
+
+// SessionState — этап обмена на соединении. После выдачи цитаты клиент
+// может снова запросить challenge
+type SessionState int
+
+const (
+	SessionConnected SessionState = iota
+	SessionChallenged
+	SessionVerified
+	SessionServed
+)
+
+var sessionStateNames = [...]string{"connected", "challenged", "verified", "served"}
+
+func (s SessionState) String() string {
+	if s < 0 || int(s) >= len(sessionStateNames) {
+		return "unknown"
+	}
+	return sessionStateNames[s]
+}
+
+// SessionStats — счётчики сессии для журнала
+type SessionStats struct {
+	State      SessionState
+	Challenges int // выдано challenge
+	Pending    int // выдано, но ещё не предъявлено и не истекло
+	Failures   int // неудачных RES
+	Served     int // выдано цитат
+}
+
+// Session — состояние одного соединения: connected → challenged → verified → served.
+// Запросы с идентификатором обрабатываются параллельно, поэтому переходы
+// проверяются для каждого обмена: verified — только для challenge, выданного
+// соединению и ещё не предъявленного, served — только после verified того же
+// RES; State показывает последний переход. Число выданных challenge ограничено,
+// чтобы их не копили про запас. Методы безопасны для конкурентного вызова
+type Session struct {
+	maxPending int
+
+	mu       sync.Mutex
+	state    SessionState
+	pending  map[string]pendingChallenge
+	reserved int
+	stats    SessionStats
+}
+
+// pendingChallenge — выданный соединению challenge: срок и надбавка за
+// репутацию на момент выдачи
+type pendingChallenge struct {
+	expiresAt time.Time
+	penalty   int
+}
+
+// NewSession создаёт сессию; maxPending ограничивает число невыполненных
+// challenge, 0 — без ограничения
+func NewSession(maxPending int) *Session {
+	return &Session{
+		maxPending: maxPending,
+		pending:    make(map[string]pendingChallenge),
+	}
+}
+
+func SessionFromContext(ctx context.Context) (*Session, bool) {
+	session, ok := ctx.Value(SessionKey).(*Session)
+	return session, ok
+}
+
+// reserve занимает место под challenge до его выдачи, release его освобождает
+func (s *Session) reserve(now time.Time) (release func(), err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prune(now)
+	if s.maxPending > 0 && len(s.pending)+s.reserved >= s.maxPending {
+		return nil, ErrTooManyChallenges
+	}
+	s.reserved++
+
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.reserved--
+	}, nil
+}
+
+// prune забывает истёкшие challenge: предъявить их уже нельзя
+func (s *Session) prune(now time.Time) {
+	for id, challenge := range s.pending {
+		if !now.Before(challenge.expiresAt) {
+			delete(s.pending, id)
+		}
+	}
+}
+
+// ChallengeIssued запоминает challenge, выданный соединению, и надбавку к его сложности
+func (s *Session) ChallengeIssued(id string, expiresAt time.Time, penalty int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pending[id] = pendingChallenge{expiresAt: expiresAt, penalty: penalty}
+	s.stats.Challenges++
+	s.state = SessionChallenged
+}
+
+// IssuedPenalty возвращает надбавку, с которой challenge выдан этому соединению
+func (s *Session) IssuedPenalty(id string) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	challenge, ok := s.pending[id]
+	if !ok || !time.Now().Before(challenge.expiresAt) {
+		return 0, false
+	}
+
+	return challenge.penalty, true
+}
+
+// Redeem снимает предъявленный challenge с сессии. RES на challenge, не
+// выданный этому соединению, истёкший или уже предъявленный, нарушает порядок
+func (s *Session) Redeem(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	challenge, ok := s.pending[id]
+	delete(s.pending, id)
+	if !ok || !time.Now().Before(challenge.expiresAt) {
+		return fmt.Errorf("%w: challenge was not issued on this connection", ErrOutOfOrder)
+	}
+
+	return nil
+}
+
+func (s *Session) verified() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state = SessionVerified
+}
+
+func (s *Session) served() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state = SessionServed
+}
+
+func (s *Session) State() SessionState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.state
+}
+
+// resolve учитывает итог RES: цитата выдана или решение отклонено
+func (s *Session) resolve(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err != nil {
+		s.stats.Failures++
+		return
+	}
+	s.stats.Served++
+}
+
+func (s *Session) Stats() SessionStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prune(time.Now())
+	stats := s.stats
+	stats.State = s.state
+	stats.Pending = len(s.pending)
+
+	return stats
+}
+
+// SessionMiddleware не выдаёт challenge сверх предела сессии и считает итоги RES.
+// Порядок команд проверяют middleware PoW через Redeem и serveVerified. Без сессии
+// в контексте сообщение проходит как есть
+func SessionMiddleware() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, conn net.Conn, clientAddr string, msg *protocol.Message) error {
+			session, ok := SessionFromContext(ctx)
+			if !ok {
+				return next(ctx, conn, clientAddr, msg)
+			}
+
+			switch msg.Command {
+			case protocol.CmdREQ:
+				release, err := session.reserve(time.Now())
+				if err != nil {
+					return err
+				}
+				defer release()
+
+				return next(ctx, conn, clientAddr, msg)
+			case protocol.CmdRES:
+				err := next(ctx, conn, clientAddr, msg)
+				session.resolve(err)
+
+				return err
+			default:
+				return next(ctx, conn, clientAddr, msg)
+			}
+		}
+	}
+}
+
+// serveVerified передаёт проверенный RES обработчику и проводит сессию
+// через verified и, если цитата выдана, served
+func serveVerified(ctx context.Context, next Handler, conn net.Conn, clientAddr string, msg *protocol.Message) error {
+	ctx = context.WithValue(ctx, VerifiedKey, true)
+
+	session, ok := SessionFromContext(ctx)
+	if !ok {
+		return next(ctx, conn, clientAddr, msg)
+	}
+
+	session.verified()
+	err := next(ctx, conn, clientAddr, msg)
+	if err == nil {
+		session.served()
+	}
+
+	return err
+}
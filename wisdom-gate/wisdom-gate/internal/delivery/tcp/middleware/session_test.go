@@ -0,0 +1,173 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"wisdom-gate/internal/adapters/redis"
+	powUC "wisdom-gate/internal/application/pow/usecase"
+	"wisdom-gate/internal/config"
+	"wisdom-gate/pkg/protocol"
+)
+
+func TestSession_PendingLimit(t *testing.T) {
+	session := NewSession(2)
+	now := time.Now()
+
+	for i, expiresAt := range []time.Time{now.Add(time.Second), now.Add(time.Minute)} {
+		release, err := session.reserve(now)
+		if err != nil {
+			t.Fatalf("reserve() #%d error = %v", i, err)
+		}
+		session.ChallengeIssued(string(rune('a'+i)), expiresAt, 0)
+		release()
+	}
+
+	if _, err := session.reserve(now); !errors.Is(err, ErrTooManyChallenges) {
+		t.Errorf("reserve() over limit error = %v, want %v", err, ErrTooManyChallenges)
+	}
+
+	// Истёкший challenge место не занимает
+	if _, err := session.reserve(now.Add(2 * time.Second)); err != nil {
+		t.Errorf("reserve() after expiry error = %v", err)
+	}
+
+	if err := session.Redeem("a"); !errors.Is(err, ErrOutOfOrder) {
+		t.Errorf("Redeem() of expired challenge error = %v, want %v", err, ErrOutOfOrder)
+	}
+	if err := session.Redeem("b"); err != nil {
+		t.Errorf("Redeem() of pending challenge error = %v", err)
+	}
+	if err := session.Redeem("b"); !errors.Is(err, ErrOutOfOrder) {
+		t.Errorf("second Redeem() error = %v, want %v", err, ErrOutOfOrder)
+	}
+}
+
+func TestSessionState_String(t *testing.T) {
+	tests := []struct {
+		state SessionState
+		want  string
+	}{
+		{state: SessionConnected, want: "connected"},
+		{state: SessionChallenged, want: "challenged"},
+		{state: SessionVerified, want: "verified"},
+		{state: SessionServed, want: "served"},
+		{state: SessionState(-1), want: "unknown"},
+		{state: SessionServed + 1, want: "unknown"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.state.String(); got != tt.want {
+			t.Errorf("SessionState(%d).String() = %q, want %q", int(tt.state), got, tt.want)
+		}
+	}
+}
+
+func TestSessionMiddleware_PoW(t *testing.T) {
+	cfg := &config.Config{
+		Redis: config.RedisConfig{
+			ChallengeTTL: time.Minute,
+			SpentTTL:     2 * time.Minute,
+		},
+		POW: config.POWConfig{
+			Difficulty: 4,
+		},
+	}
+
+	registry, err := powUC.NewRegistry(protocol.AlgorithmSha256)
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+
+	challengeStore := powUC.NewStoredChallengeStore(redis.NewMockRedisClient(), cfg.Redis.ChallengeTTL, cfg.Redis.SpentTTL)
+	difficulty := newTestDifficulty(cfg)
+	handler := Chain(
+		SessionMiddleware(),
+		PoWChallengeMiddleware(challengeStore, registry, difficulty, newTestReputation(), cfg),
+		PoWVerificationMiddleware(challengeStore, newTestVerifier(t), difficulty, newTestReputation(), cfg),
+	)(func(ctx context.Context, conn net.Conn, clientAddr string, msg *protocol.Message) error {
+		return nil
+	})
+
+	// requestChallenge выдаёт challenge на соединении с сессией и решает его
+	requestChallenge := func(ctx context.Context) (*protocol.Message, error) {
+		conn := &mockConn{}
+		if err := handler(ctx, conn, "127.0.0.1", &protocol.Message{Command: protocol.CmdREQ}); err != nil {
+			return nil, err
+		}
+
+		msg, err := protocol.ReadMessage(bufio.NewReader(bytes.NewReader(conn.writtenData[0])))
+		if err != nil {
+			t.Fatalf("ReadMessage() error = %v", err)
+		}
+		solution, err := protocol.ParseHashcashHeader(msg.Body)
+		if err != nil {
+			t.Fatalf("ParseHashcashHeader() error = %v", err)
+		}
+		solution.Counter = solveForTest(t, solution)
+
+		return &protocol.Message{Command: protocol.CmdRES, Body: solution.String()}, nil
+	}
+
+	first := NewSession(1)
+	firstCtx := context.WithValue(context.Background(), SessionKey, first)
+	res, err := requestChallenge(firstCtx)
+	if err != nil {
+		t.Fatalf("REQ error = %v", err)
+	}
+
+	if _, err := requestChallenge(firstCtx); !errors.Is(err, ErrTooManyChallenges) {
+		t.Errorf("second REQ error = %v, want %v", err, ErrTooManyChallenges)
+	}
+
+	// Решение challenge, выданного другому соединению, отклоняется
+	second := NewSession(1)
+	secondCtx := context.WithValue(context.Background(), SessionKey, second)
+	if err := handler(secondCtx, &mockConn{}, "127.0.0.1", res); !errors.Is(err, ErrOutOfOrder) {
+		t.Errorf("RES on another connection error = %v, want %v", err, ErrOutOfOrder)
+	}
+
+	if err := handler(firstCtx, &mockConn{}, "127.0.0.1", res); err != nil {
+		t.Fatalf("RES error = %v", err)
+	}
+
+	want := SessionStats{State: SessionServed, Challenges: 1, Served: 1}
+	if got := first.Stats(); got != want {
+		t.Errorf("first Stats() = %+v, want %+v", got, want)
+	}
+	want = SessionStats{State: SessionConnected, Failures: 1}
+	if got := second.Stats(); got != want {
+		t.Errorf("second Stats() = %+v, want %+v", got, want)
+	}
+}
+
+func TestServeVerified_HandlerFailure(t *testing.T) {
+	session := NewSession(0)
+	session.ChallengeIssued("a", time.Now().Add(time.Minute), 0)
+	ctx := context.WithValue(context.Background(), SessionKey, session)
+
+	errQuotes := errors.New("quotes unavailable")
+	next := func(ctx context.Context, conn net.Conn, clientAddr string, msg *protocol.Message) error {
+		if verified, _ := ctx.Value(VerifiedKey).(bool); !verified {
+			t.Error("serveVerified() did not mark context as verified")
+		}
+		return errQuotes
+	}
+
+	if err := session.Redeem("a"); err != nil {
+		t.Fatalf("Redeem() error = %v", err)
+	}
+	if err := serveVerified(ctx, next, &mockConn{}, "127.0.0.1", &protocol.Message{Command: protocol.CmdRES}); !errors.Is(err, errQuotes) {
+		t.Errorf("serveVerified() error = %v, want %v", err, errQuotes)
+	}
+
+	// Цитата не выдана: сессия остаётся в verified
+	if got := session.State(); got != SessionVerified {
+		t.Errorf("State() = %v, want %v", got, SessionVerified)
+	}
+}
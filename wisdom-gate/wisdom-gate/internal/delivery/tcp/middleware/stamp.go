@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	powUC "wisdom-gate/internal/application/pow/usecase"
+	reputationUC "wisdom-gate/internal/application/reputation/usecase"
+	"wisdom-gate/pkg/protocol"
+)
+
+// HashcashChallengeMiddleware выдаёт на REQ шаблон марки Hashcash v1 вместо
+// собственного заголовка. Алгоритм всегда SHA-1, сложность — в битах
+func HashcashChallengeMiddleware(
+	stamps *powUC.StampStore,
+	difficulty *powUC.DifficultyController,
+	reputation *reputationUC.ReputationUseCase,
+) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, conn net.Conn, clientAddr string, msg *protocol.Message) error {
+			if msg.Command != protocol.CmdREQ {
+				return next(ctx, conn, clientAddr, msg)
+			}
+
+			penalty := reputationPenalty(ctx, reputation, clientAddr)
+			stamp, err := stamps.Issue(difficulty.Current() + penalty)
+			if err != nil {
+				return fmt.Errorf("%w: failed to issue stamp: %w", ErrUnavailable, err)
+			}
+			difficulty.ObserveIssue()
+			if session, ok := SessionFromContext(ctx); ok {
+				session.ChallengeIssued(stamp.Rand, time.Now().Add(stamps.Validity()), penalty)
+			}
+
+			challengeMsg := &protocol.Message{
+				Command: protocol.CmdCHL,
+				Body:    stamp.String(),
+			}
+
+			if err := protocol.WriteMessage(conn, challengeMsg); err != nil {
+				return fmt.Errorf("failed to send challenge: %w", err)
+			}
+
+			return nil
+		}
+	}
+}
+
+// HashcashVerificationMiddleware принимает в RES марку Hashcash v1, в том числе
+// выпущенную сторонней утилитой без предварительного REQ
+func HashcashVerificationMiddleware(
+	stamps *powUC.StampStore,
+	powVerifier powUC.VerifierInterface,
+	difficulty *powUC.DifficultyController,
+	reputation *reputationUC.ReputationUseCase,
+) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, conn net.Conn, clientAddr string, msg *protocol.Message) error {
+			if msg.Command != protocol.CmdRES {
+				return next(ctx, conn, clientAddr, msg)
+			}
+
+			err := verifyStamp(ctx, stamps, powVerifier, difficulty, reputation, clientAddr, msg.Body)
+			observeVerification(ctx, difficulty, reputation, clientAddr, err)
+			if err != nil {
+				return err
+			}
+
+			return serveVerified(ctx, next, conn, clientAddr, msg)
+		}
+	}
+}
+
+func verifyStamp(
+	ctx context.Context,
+	stamps *powUC.StampStore,
+	powVerifier powUC.VerifierInterface,
+	difficulty *powUC.DifficultyController,
+	reputation *reputationUC.ReputationUseCase,
+	clientAddr string,
+	solution string,
+) error {
+	stamp, err := protocol.ParseHashcashStamp(solution)
+	if err != nil {
+		return fmt.Errorf("%w: invalid stamp format: %w", ErrMalformedSolution, err)
+	}
+
+	var (
+		penalty int
+		issued  bool
+	)
+	session, hasSession := SessionFromContext(ctx)
+	if hasSession {
+		penalty, issued = session.IssuedPenalty(stamp.Rand)
+	}
+
+	// Уровень мог вырасти, пока клиент решал выданную соединению марку, поэтому
+	// она сверяется с нижней границей и надбавкой на момент выдачи. Сторонняя
+	// марка к выдаче не привязана, для неё действует текущий уровень
+	requiredBits := difficulty.Min() + penalty
+	if !issued {
+		requiredBits = difficulty.Current() + reputationPenalty(ctx, reputation, clientAddr)
+	}
+	if stamp.Bits < requiredBits {
+		return fmt.Errorf("%w: below required", protocol.ErrDifficultyMismatch)
+	}
+
+	// Хеш проверяется до записи в множество потраченных, чтобы мусор его не заполнял
+	if !powVerifier.VerifyStamp(solution, stamp.Bits) {
+		return ErrInsufficientWork
+	}
+
+	// Выданная соединению марка снимается с сессии, повторный RES на неё нарушает
+	// порядок. Марку сторонней утилиты сервер не выдавал, она принимается без REQ
+	if issued {
+		if err := session.Redeem(stamp.Rand); err != nil {
+			return err
+		}
+	}
+
+	if err := stamps.Redeem(ctx, stamp); err != nil {
+		return redeemError(err)
+	}
+
+	return nil
+}
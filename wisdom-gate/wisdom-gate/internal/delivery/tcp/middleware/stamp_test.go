@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"wisdom-gate/internal/adapters/redis"
+	powUC "wisdom-gate/internal/application/pow/usecase"
+	"wisdom-gate/pkg/protocol"
+)
+
+func TestHashcashMiddleware(t *testing.T) {
+	stamps := newTestStampStore(t)
+	difficulty := powUC.NewDifficultyController(8, 8, 8, powUC.LoadThresholds{})
+	reputation := newTestReputation()
+	verifier := newTestVerifier(t)
+
+	verified := false
+	nextHandler := func(ctx context.Context, conn net.Conn, clientAddr string, msg *protocol.Message) error {
+		verified, _ = ctx.Value(VerifiedKey).(bool)
+		return nil
+	}
+
+	challengeHandler := HashcashChallengeMiddleware(stamps, difficulty, reputation)(nextHandler)
+	verifyHandler := HashcashVerificationMiddleware(stamps, verifier, difficulty, reputation)(nextHandler)
+
+	conn := &mockConn{}
+	if err := challengeHandler(context.Background(), conn, "127.0.0.1", &protocol.Message{Command: protocol.CmdREQ}); err != nil {
+		t.Fatalf("HashcashChallengeMiddleware() error = %v", err)
+	}
+
+	msg, err := protocol.ReadMessage(bufio.NewReader(bytes.NewReader(conn.writtenData[0])))
+	if err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+	stamp, err := protocol.ParseHashcashStamp(msg.Body)
+	if err != nil {
+		t.Fatalf("ParseHashcashStamp(%q) error = %v", msg.Body, err)
+	}
+
+	solveStamp(verifier, stamp)
+
+	resMsg := &protocol.Message{Command: protocol.CmdRES, Body: stamp.String()}
+	if err := verifyHandler(context.Background(), &mockConn{}, "127.0.0.1", resMsg); err != nil {
+		t.Fatalf("HashcashVerificationMiddleware() error = %v", err)
+	}
+	if !verified {
+		t.Error("HashcashVerificationMiddleware() did not mark context as verified")
+	}
+
+	if err := verifyHandler(context.Background(), &mockConn{}, "127.0.0.1", resMsg); !errors.Is(err, powUC.ErrChallengeSpent) {
+		t.Errorf("HashcashVerificationMiddleware() replay error = %v, want %v", err, powUC.ErrChallengeSpent)
+	}
+
+	weak := *stamp
+	weak.Bits = 4
+	weakMsg := &protocol.Message{Command: protocol.CmdRES, Body: weak.String()}
+	if err := verifyHandler(context.Background(), &mockConn{}, "127.0.0.1", weakMsg); !errors.Is(err, protocol.ErrDifficultyMismatch) {
+		t.Errorf("HashcashVerificationMiddleware() weak stamp error = %v, want %v", err, protocol.ErrDifficultyMismatch)
+	}
+}
+
+func TestHashcashMiddleware_IssuedBits(t *testing.T) {
+	stamps := newTestStampStore(t)
+	// Уровень выше нижней границы: выданная марка сверяется с границей
+	difficulty := powUC.NewDifficultyController(12, 8, 16, powUC.LoadThresholds{})
+	reputation := newTestReputation()
+	verifier := newTestVerifier(t)
+
+	nextHandler := func(ctx context.Context, conn net.Conn, clientAddr string, msg *protocol.Message) error {
+		return nil
+	}
+	challengeHandler := HashcashChallengeMiddleware(stamps, difficulty, reputation)(nextHandler)
+	verifyHandler := HashcashVerificationMiddleware(stamps, verifier, difficulty, reputation)(nextHandler)
+
+	issue := func(ctx context.Context) *protocol.HashcashStamp {
+		t.Helper()
+
+		conn := &mockConn{}
+		if err := challengeHandler(ctx, conn, "127.0.0.1", &protocol.Message{Command: protocol.CmdREQ}); err != nil {
+			t.Fatalf("HashcashChallengeMiddleware() error = %v", err)
+		}
+		msg, err := protocol.ReadMessage(bufio.NewReader(bytes.NewReader(conn.writtenData[0])))
+		if err != nil {
+			t.Fatalf("ReadMessage() error = %v", err)
+		}
+		stamp, err := protocol.ParseHashcashStamp(msg.Body)
+		if err != nil {
+			t.Fatalf("ParseHashcashStamp(%q) error = %v", msg.Body, err)
+		}
+
+		// Решаем на нижней границе, как если бы challenge выдали до подъёма уровня
+		stamp.Bits = difficulty.Min()
+		solveStamp(verifier, stamp)
+
+		return stamp
+	}
+
+	session := NewSession(0)
+	ctx := context.WithValue(context.Background(), SessionKey, session)
+	issued := issue(ctx)
+	if got := session.State(); got != SessionChallenged {
+		t.Errorf("State() after REQ = %v, want %v", got, SessionChallenged)
+	}
+	if err := verifyHandler(ctx, &mockConn{}, "127.0.0.1", &protocol.Message{Command: protocol.CmdRES, Body: issued.String()}); err != nil {
+		t.Errorf("issued stamp at min bits error = %v, want nil", err)
+	}
+	if got := session.Stats(); got.State != SessionServed || got.Pending != 0 {
+		t.Errorf("Stats() after RES = %+v, want served without pending", got)
+	}
+
+	// На другом соединении марка сторонняя и сверяется с текущим уровнем
+	foreign := issue(ctx)
+	otherCtx := context.WithValue(context.Background(), SessionKey, NewSession(0))
+	err := verifyHandler(otherCtx, &mockConn{}, "127.0.0.1", &protocol.Message{Command: protocol.CmdRES, Body: foreign.String()})
+	if !errors.Is(err, protocol.ErrDifficultyMismatch) {
+		t.Errorf("foreign stamp at min bits error = %v, want %v", err, protocol.ErrDifficultyMismatch)
+	}
+}
+
+// solveStamp подбирает counter так же, как это делает утилита hashcash
+func solveStamp(verifier powUC.VerifierInterface, stamp *protocol.HashcashStamp) {
+	for i := 0; ; i++ {
+		stamp.Counter = strconv.FormatInt(int64(i), 36)
+		if verifier.VerifyStamp(stamp.String(), stamp.Bits) {
+			return
+		}
+	}
+}
+
+func newTestStampStore(t *testing.T) *powUC.StampStore {
+	t.Helper()
+
+	stamps, err := powUC.NewStampStore(redis.NewMockRedisClient(), "wisdom-gate", 20*time.Second, time.Hour)
+	if err != nil {
+		t.Fatalf("NewStampStore() error = %v", err)
+	}
+
+	return stamps
+}
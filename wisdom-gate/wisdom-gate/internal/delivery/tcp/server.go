@@ -3,19 +3,25 @@ package tcp
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"net"
 	"sync"
 	"time"
 
+	"wisdom-gate/internal/adapters/memory"
 	"wisdom-gate/internal/adapters/postgres"
 	"wisdom-gate/internal/adapters/redis"
 	powUC "wisdom-gate/internal/application/pow/usecase"
 	quotesUC "wisdom-gate/internal/application/quotes/usecase"
+	reputationUC "wisdom-gate/internal/application/reputation/usecase"
 	"wisdom-gate/internal/config"
+	"wisdom-gate/internal/delivery/tcp/identity"
 	"wisdom-gate/internal/delivery/tcp/middleware"
 	v1 "wisdom-gate/internal/delivery/tcp/v1"
 	"wisdom-gate/internal/delivery/tcp/v1/handlers"
+	v2 "wisdom-gate/internal/delivery/tcp/v2"
+	"wisdom-gate/pkg/protocol"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -27,34 +33,136 @@ type Server struct {
 	listener    net.Listener
 	wg          sync.WaitGroup
 	shutdownCh  chan struct{}
-	redisClient redis.ClientInterface
+	redisClient *redis.Client
+	memoryStore *memory.ChallengeStore
+	limiter     middleware.Limiter
+	identity    *identity.Extractor
+	difficulty  *powUC.DifficultyController
+	connLimiter *ConnLimiter
+	errors      *middleware.ErrorCatalogue
 }
 
 func NewServer(cfg *config.Config, logger *slog.Logger, db *pgxpool.Pool) (*Server, error) {
-	redisClient, err := redis.NewClient(cfg.Redis.Addr)
+	// Redis нужен, только если его использует хоть одно хранилище
+	var redisClient *redis.Client
+	if cfg.POW.ChallengeBackend == config.BackendRedis ||
+		cfg.Reputation.Backend == config.BackendRedis ||
+		cfg.Server.RateLimitBackend == config.BackendRedis {
+		var err error
+		redisClient, err = redis.NewClient(redisOptions(cfg.Redis))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Redis client: %w", err)
+		}
+	}
+
+	var (
+		challengeRepo redis.ClientInterface
+		memoryStore   *memory.ChallengeStore
+	)
+	switch cfg.POW.ChallengeBackend {
+	case config.BackendRedis:
+		challengeRepo = redisClient
+	case config.BackendMemory:
+		var err error
+		memoryStore, err = memory.NewChallengeStore(cfg.POW.ChallengeMaxEntries, cfg.POW.ChallengeSweepInterval)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create memory challenge store: %w", err)
+		}
+		challengeRepo = memoryStore
+	default:
+		return nil, fmt.Errorf("unknown challenge backend: %s", cfg.POW.ChallengeBackend)
+	}
+
+	challengeStore, err := newChallengeStore(cfg, challengeRepo)
+	if err != nil {
+		return nil, err
+	}
+
+	registry, err := powUC.NewRegistry(cfg.POW.Algorithms...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create algorithm registry: %w", err)
+	}
+	registry.Register(powUC.NewArgon2Algorithm(protocol.Argon2Params{
+		Memory:  cfg.POW.Argon2Memory,
+		Time:    cfg.POW.Argon2Time,
+		Threads: cfg.POW.Argon2Threads,
+	}, cfg.POW.Argon2MaxConcurrent))
+
+	difficulty := newDifficultyController(cfg)
+
+	reputation, err := newReputation(cfg, redisClient)
+	if err != nil {
+		return nil, err
+	}
+
+	limiter, err := newRateLimiter(cfg, redisClient)
+	if err != nil {
+		return nil, err
+	}
+
+	clientIdentity, err := identity.NewExtractor(cfg.Server.ClientIPv4Prefix, cfg.Server.ClientIPv6Prefix)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create Redis client: %w", err)
+		return nil, fmt.Errorf("failed to create client identity extractor: %w", err)
 	}
 
 	quoteRepo := postgres.NewQuotesRepository(db)
-	powVerifier := powUC.NewVerifier()
+	powVerifier := powUC.NewVerifier(registry)
 	quotesUsecase := quotesUC.NewQuotesUseCase(quoteRepo)
 
 	quotesHandler := handlers.NewQuotesHandler(quotesUsecase)
 	connectionHandler := handlers.NewConnectionHandler()
-	handlersCollection := handlers.NewHandlers(quotesHandler, connectionHandler)
+	powHandler := handlers.NewPoWHandler(registry)
+	handlersCollection := handlers.NewHandlers(quotesHandler, connectionHandler, powHandler)
+
+	var challengeMiddleware, verificationMiddleware middleware.Middleware
+	switch cfg.POW.StampFormat {
+	case config.StampFormatWisdom:
+		challengeMiddleware = middleware.PoWChallengeMiddleware(challengeStore, registry, difficulty, reputation, cfg)
+		verificationMiddleware = middleware.PoWVerificationMiddleware(challengeStore, powVerifier, difficulty, reputation, cfg)
+	case config.StampFormatHashcash:
+		stamps, err := powUC.NewStampStore(challengeRepo, cfg.POW.HashcashResource, cfg.POW.StampValidity, cfg.POW.StampMaxSpentTTL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stamp store: %w", err)
+		}
+		challengeMiddleware = middleware.HashcashChallengeMiddleware(stamps, difficulty, reputation)
+		verificationMiddleware = middleware.HashcashVerificationMiddleware(stamps, powVerifier, difficulty, reputation)
+	default:
+		return nil, fmt.Errorf("unknown stamp format: %s", cfg.POW.StampFormat)
+	}
+
+	errorCatalogue := newErrorCatalogue(cfg)
 
 	middlewareChain := middleware.Chain(
-		middleware.TimeoutMiddleware(cfg.Server.ReadTimeout),
+		middleware.TimeoutMiddleware(cfg.Server.WriteTimeout),
 		middleware.LoggingMiddleware(),
-		middleware.RateLimitMiddleware(middleware.NewRateLimiter(cfg.Server.RateLimit, cfg.Server.RateWindow)),
-		middleware.PoWChallengeMiddleware(redisClient, cfg),
-		middleware.PoWVerificationMiddleware(redisClient, powVerifier, cfg, logger),
-		middleware.ErrorHandlerMiddleware(),
+		middleware.LatencyMiddleware(difficulty),
+		// Ошибки лимита и проверки решения тоже должны дойти до клиента как ERR
+		middleware.ErrorHandlerMiddleware(errorCatalogue),
+		middleware.RateLimitMiddleware(limiter, reputation),
+		middleware.SessionMiddleware(),
+		challengeMiddleware,
+		verificationMiddleware,
 	)
 
-	api := v1.NewAPI(*handlersCollection, middlewareChain)
-	handler := NewHandler(api, logger)
+	apis := map[int]API{
+		protocol.ProtocolV1: v1.NewAPI(*handlersCollection, middlewareChain),
+		protocol.ProtocolV2: v2.NewAPI(*handlersCollection, middlewareChain),
+	}
+	handshake := NewHandshake(registry, difficulty, []int{protocol.ProtocolV1, protocol.ProtocolV2}, cfg.Server.MaxFrameSize)
+	handler := NewHandler(apis, handshake, errorCatalogue, logger, HandlerOptions{
+		MaxFrameSize:     cfg.Server.MaxFrameSize,
+		MaxInflight:      cfg.Server.MaxInflight,
+		HandshakeTimeout: cfg.Server.ReadTimeout,
+		IdleTimeout:      cfg.Server.IdleTimeout,
+		MaxLifetime:      cfg.Server.MaxConnLifetime,
+		WriteTimeout:     cfg.Server.WriteTimeout,
+		Guard: GuardOptions{
+			FrameTimeout: cfg.Server.FrameTimeout,
+			MinRate:      cfg.Server.MinTransferRate,
+			Grace:        cfg.Server.TransferGrace,
+		},
+		MaxPendingChallenges: cfg.POW.MaxPendingChallenges,
+	})
 
 	return &Server{
 		config:      cfg,
@@ -62,9 +170,122 @@ func NewServer(cfg *config.Config, logger *slog.Logger, db *pgxpool.Pool) (*Serv
 		handler:     handler,
 		shutdownCh:  make(chan struct{}),
 		redisClient: redisClient,
+		memoryStore: memoryStore,
+		limiter:     limiter,
+		identity:    clientIdentity,
+		difficulty:  difficulty,
+		connLimiter: NewConnLimiter(cfg.Server.MaxConns, cfg.Server.MaxConnsPerIP),
+		errors:      errorCatalogue,
 	}, nil
 }
 
+func redisOptions(cfg config.RedisConfig) redis.Options {
+	return redis.Options{
+		URL:                   cfg.URL,
+		Addr:                  cfg.Addr,
+		Username:              cfg.Username,
+		Password:              cfg.Password,
+		DB:                    cfg.DB,
+		TLS:                   cfg.TLS,
+		TLSServerName:         cfg.TLSServerName,
+		TLSInsecureSkipVerify: cfg.TLSInsecureSkipVerify,
+		SentinelMaster:        cfg.SentinelMaster,
+		SentinelAddrs:         cfg.SentinelAddrs,
+		SentinelPassword:      cfg.SentinelPassword,
+		ClusterAddrs:          cfg.ClusterAddrs,
+	}
+}
+
+func newChallengeStore(cfg *config.Config, repo redis.ClientInterface) (powUC.ChallengeStoreInterface, error) {
+	switch cfg.POW.ChallengeMode {
+	case config.ChallengeModeStored:
+		return powUC.NewStoredChallengeStore(repo, cfg.Redis.ChallengeTTL, cfg.Redis.SpentTTL), nil
+	case config.ChallengeModeStateless:
+		signer, err := powUC.NewSigner(cfg.POW.HMACSecret, cfg.POW.HMACPreviousSecret)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create challenge signer: %w", err)
+		}
+		return powUC.NewStatelessChallengeStore(signer, repo, cfg.Redis.SpentTTL), nil
+	default:
+		return nil, fmt.Errorf("unknown challenge mode: %s", cfg.POW.ChallengeMode)
+	}
+}
+
+func newReputation(cfg *config.Config, redisClient *redis.Client) (*reputationUC.ReputationUseCase, error) {
+	rc := cfg.Reputation
+
+	switch rc.Backend {
+	case config.BackendMemory:
+		store := memory.NewReputationStore(rc.MaxClients)
+		return reputationUC.NewReputationUseCase(store, rc.HalfLife, rc.PenaltyStep, rc.MaxPenalty), nil
+	case config.BackendRedis:
+		store := redis.NewReputationStore(redisClient)
+		return reputationUC.NewReputationUseCase(store, rc.HalfLife, rc.PenaltyStep, rc.MaxPenalty), nil
+	default:
+		return nil, fmt.Errorf("unknown reputation backend: %s", rc.Backend)
+	}
+}
+
+func newRateLimiter(cfg *config.Config, redisClient *redis.Client) (middleware.Limiter, error) {
+	switch cfg.Server.RateLimitBackend {
+	case config.BackendMemory:
+		limiter, err := middleware.NewRateLimiter(cfg.Server.RateLimit, cfg.Server.RateWindow)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create rate limiter: %w", err)
+		}
+		return limiter, nil
+	case config.BackendRedis:
+		return redis.NewRateLimiter(redisClient, cfg.Server.RateLimit, cfg.Server.RateWindow), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit backend: %s", cfg.Server.RateLimitBackend)
+	}
+}
+
+const (
+	// unavailableRetryAfter — подсказка клиенту при временной недоступности хранилищ
+	unavailableRetryAfter = time.Second
+	// busyRetryAfter — подсказка клиенту, которому отказано из-за числа соединений
+	busyRetryAfter = time.Second
+	// rejectWriteTimeout — сколько ждать отправки ERR соединению сверх лимита
+	rejectWriteTimeout = time.Second
+)
+
+// newErrorCatalogue подсказывает повтор после лимита через время пополнения одного токена
+func newErrorCatalogue(cfg *config.Config) *middleware.ErrorCatalogue {
+	retryAfter := map[protocol.ErrorCode]time.Duration{
+		protocol.CodeUnavailable: unavailableRetryAfter,
+		protocol.CodeBusy:        busyRetryAfter,
+	}
+	if cfg.Server.RateLimit > 0 {
+		retryAfter[protocol.CodeRateLimited] = cfg.Server.RateWindow / time.Duration(cfg.Server.RateLimit)
+	}
+
+	catalogue := middleware.NewErrorCatalogue(retryAfter)
+	catalogue.Register(ErrNoCommonVersion, protocol.CodeUnsupported)
+	catalogue.Register(ErrNoCommonAlgorithm, protocol.CodeUnsupported)
+	catalogue.Register(ErrInvalidHello, protocol.CodeBadRequest)
+	catalogue.Register(ErrServerBusy, protocol.CodeBusy)
+	catalogue.Register(ErrTooManyConnections, protocol.CodeBusy)
+	catalogue.Register(powUC.ErrVerifierBusy, protocol.CodeBusy)
+
+	return catalogue
+}
+
+// newDifficultyController без POW_ADAPTIVE фиксирует сложность на POW_DIFFICULTY
+func newDifficultyController(cfg *config.Config) *powUC.DifficultyController {
+	minDifficulty, maxDifficulty := cfg.POW.Difficulty, cfg.POW.Difficulty
+	if cfg.POW.Adaptive {
+		minDifficulty, maxDifficulty = cfg.POW.DifficultyMin, cfg.POW.DifficultyMax
+	}
+
+	return powUC.NewDifficultyController(cfg.POW.Difficulty, minDifficulty, maxDifficulty, powUC.LoadThresholds{
+		ActiveConns: cfg.POW.LoadConnections,
+		IssueRate:   cfg.POW.LoadIssueRate,
+		FailureRate: cfg.POW.LoadFailureRate,
+		Latency:     cfg.POW.LoadLatency,
+	})
+}
+
 func (s *Server) Start(ctx context.Context) error {
 	addr := ":" + s.config.Server.Port
 	listener, err := net.Listen("tcp", addr)
@@ -77,6 +298,14 @@ func (s *Server) Start(ctx context.Context) error {
 
 	go s.acceptConnections(ctx)
 
+	if s.config.POW.Adaptive {
+		go s.adjustDifficulty(ctx)
+	}
+
+	if s.config.Server.StatsInterval > 0 {
+		go s.reportStats(ctx)
+	}
+
 	select {
 	case <-ctx.Done():
 		s.logger.Info("Shutdown signal received")
@@ -111,6 +340,14 @@ func (s *Server) Shutdown(ctx context.Context) error {
 		s.logger.Warn("Shutdown timeout exceeded, forcing close")
 	}
 
+	if s.memoryStore != nil {
+		_ = s.memoryStore.Close()
+	}
+
+	if closer, ok := s.limiter.(io.Closer); ok {
+		_ = closer.Close()
+	}
+
 	if s.redisClient != nil {
 		if err := s.redisClient.Close(); err != nil {
 			s.logger.Error("Failed to close Redis client", "error", err)
@@ -142,27 +379,126 @@ func (s *Server) acceptConnections(ctx context.Context) {
 				}
 			}
 
+			// Лимиты, subject challenge и репутация привязаны к клиенту, а не к соединению
+			clientAddr := s.identity.FromAddr(conn.RemoteAddr())
+			if err := s.connLimiter.Acquire(clientAddr); err != nil {
+				s.reject(conn, clientAddr, err)
+				continue
+			}
+
 			s.wg.Add(1)
-			go s.handleConnection(ctx, conn)
+			go s.handleConnection(ctx, conn, clientAddr)
+		}
+	}
+}
+
+func (s *Server) adjustDifficulty(ctx context.Context) {
+	ticker := time.NewTicker(s.config.POW.AdjustInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.shutdownCh:
+			return
+		case now := <-ticker.C:
+			d := s.difficulty.Evaluate(now)
+			level := slog.LevelDebug
+			if d.From != d.To {
+				level = slog.LevelInfo
+			}
+			s.logger.Log(ctx, level, "PoW difficulty evaluated",
+				"from", d.From, "to", d.To, "reason", d.Reason,
+				"conns", d.Sample.ActiveConns, "issue_rate", d.Sample.IssueRate,
+				"failure_rate", d.Sample.FailureRate, "latency", d.Sample.Latency)
+		}
+	}
+}
+
+// reportStats периодически пишет в журнал состояние сервера для отладки
+func (s *Server) reportStats(ctx context.Context) {
+	ticker := time.NewTicker(s.config.Server.StatsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.shutdownCh:
+			return
+		case <-ticker.C:
+			s.logDifficulty()
+			s.logRateLimiter()
 		}
 	}
 }
 
-func (s *Server) handleConnection(ctx context.Context, conn net.Conn) {
+// logDifficulty сводит историю решений контроллера сложности
+func (s *Server) logDifficulty() {
+	history := s.difficulty.History()
+
+	var raised, lowered int
+	var last powUC.Decision
+	for _, d := range history {
+		switch {
+		case d.To > d.From:
+			raised++
+		case d.To < d.From:
+			lowered++
+		default:
+			continue
+		}
+		last = d
+	}
+
+	attrs := []any{"current", s.difficulty.Current(), "min", s.difficulty.Min(),
+		"decisions", len(history), "raised", raised, "lowered", lowered}
+	if !last.At.IsZero() {
+		attrs = append(attrs, "last_change", last.At, "last_reason", last.Reason)
+	}
+	s.logger.Info("PoW difficulty stats", attrs...)
+}
+
+// logRateLimiter сообщает, сколько ключей держит лимитер в памяти и сколько вытеснено.
+// Лимитер в Redis своё состояние хранит там
+func (s *Server) logRateLimiter() {
+	limiter, ok := s.limiter.(*middleware.RateLimiter)
+	if !ok {
+		return
+	}
+
+	stats := limiter.Stats()
+	s.logger.Info("Rate limiter stats", "keys", stats.Keys, "evicted", stats.Evicted)
+}
+
+// reject отвечает соединению сверх лимита ERR с подсказкой повтора и сразу
+// закрывает его, не занимая горутину. Формат клиента ещё неизвестен, ответ идёт текстом v1
+func (s *Server) reject(conn net.Conn, clientAddr string, err error) {
+	_ = conn.SetWriteDeadline(time.Now().Add(rejectWriteTimeout))
+	_ = s.errors.WriteError(conn, err)
+	_ = conn.Close()
+
+	s.logger.Warn("Connection rejected", "client", clientAddr, "addr", conn.RemoteAddr().String(), "reason", err)
+}
+
+func (s *Server) handleConnection(ctx context.Context, conn net.Conn, clientAddr string) {
+	s.difficulty.ConnOpened()
 	defer func() {
 		_ = conn.Close()
+		s.connLimiter.Release(clientAddr)
+		s.difficulty.ConnClosed()
 		s.wg.Done()
 	}()
 
-	func() { _ = conn.SetReadDeadline(time.Now().Add(s.config.Server.ReadTimeout)) }()
-	func() { _ = conn.SetWriteDeadline(time.Now().Add(s.config.Server.WriteTimeout)) }()
-
-	clientAddr := conn.RemoteAddr().String()
-	s.logger.Info("New connection", "addr", clientAddr)
+	s.logger.Info("New connection", "client", clientAddr, "addr", conn.RemoteAddr().String())
 
-	if err := s.handler.HandleConnection(ctx, conn, clientAddr); err != nil {
-		s.logger.Error("Error handling client", "addr", clientAddr, "error", err)
+	// Сроки чтения и записи, включая простой и время жизни, выставляет Handler
+	err := s.handler.HandleConnection(ctx, conn, clientAddr)
+	reason := CloseReason(err)
+	if reason == protocol.CloseReasonProtocol || reason == protocol.CloseReasonError {
+		s.logger.Error("Error handling client", "client", clientAddr, "error", err)
 	}
 
-	s.logger.Info("Connection closed", "addr", clientAddr)
+	s.logger.Info("Connection closed", "client", clientAddr, "reason", reason)
 }
@@ -4,10 +4,10 @@ import (
 	"context"
 	"net"
 
-	protocolUC "wisdom-gate/internal/application/protocol/usecase"
 	"wisdom-gate/internal/delivery/tcp/middleware"
 	"wisdom-gate/internal/delivery/tcp/v1/handlers"
 	clientRoutesV1 "wisdom-gate/internal/delivery/tcp/v1/routes"
+	"wisdom-gate/pkg/protocol"
 )
 
 type API struct {
@@ -22,6 +22,6 @@ func NewAPI(handlers handlers.Handlers, middlewareChain middleware.Middleware) *
 	}
 }
 
-func (api *API) HandleMessage(ctx context.Context, conn net.Conn, clientAddr string, msg *protocolUC.Message) error {
+func (api *API) HandleMessage(ctx context.Context, conn net.Conn, clientAddr string, msg *protocol.Message) error {
 	return clientRoutesV1.Route(ctx, conn, clientAddr, msg, api.handlers, api.middlewareChain)
 }
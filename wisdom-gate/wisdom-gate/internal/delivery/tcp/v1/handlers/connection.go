@@ -4,7 +4,7 @@ import (
 	"context"
 	"net"
 
-	protocolUC "wisdom-gate/internal/application/protocol/usecase"
+	"wisdom-gate/pkg/protocol"
 )
 
 type ConnectionHandler struct{}
@@ -13,10 +13,10 @@ func NewConnectionHandler() *ConnectionHandler {
 	return &ConnectionHandler{}
 }
 
-func (h *ConnectionHandler) HandleDisconnect(ctx context.Context, conn net.Conn, clientAddr string, msg *protocolUC.Message) error {
-	okMsg := &protocolUC.Message{
+func (h *ConnectionHandler) HandleDisconnect(ctx context.Context, conn net.Conn, clientAddr string, msg *protocol.Message) error {
+	okMsg := &protocol.Message{
 		Command: "небольшой расход",
 	}
 
-	return protocolUC.WriteMessage(conn, okMsg)
+	return protocol.WriteMessage(conn, okMsg)
 }
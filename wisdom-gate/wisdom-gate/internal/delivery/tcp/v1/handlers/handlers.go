@@ -3,11 +3,13 @@ package handlers
 type Handlers struct {
 	QuotesHandler     *QuotesHandler
 	ConnectionHandler *ConnectionHandler
+	PoWHandler        *PoWHandler
 }
 
-func NewHandlers(quotesHandler *QuotesHandler, connectionHandler *ConnectionHandler) *Handlers {
+func NewHandlers(quotesHandler *QuotesHandler, connectionHandler *ConnectionHandler, powHandler *PoWHandler) *Handlers {
 	return &Handlers{
 		QuotesHandler:     quotesHandler,
 		ConnectionHandler: connectionHandler,
+		PoWHandler:        powHandler,
 	}
 }
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	powUC "wisdom-gate/internal/application/pow/usecase"
+	"wisdom-gate/pkg/protocol"
+)
+
+type PoWHandler struct {
+	registry *powUC.Registry
+}
+
+func NewPoWHandler(registry *powUC.Registry) *PoWHandler {
+	return &PoWHandler{
+		registry: registry,
+	}
+}
+
+// HandleAlgorithms сообщает клиенту разрешённые алгоритмы в порядке предпочтения сервера
+func (h *PoWHandler) HandleAlgorithms(ctx context.Context, conn net.Conn, clientAddr string, msg *protocol.Message) error {
+	algMsg := &protocol.Message{
+		Command: protocol.CmdALG,
+		Body:    strings.Join(h.registry.Enabled(), protocol.AlgorithmSeparator),
+	}
+
+	return protocol.WriteMessage(conn, algMsg)
+}
@@ -5,10 +5,9 @@ import (
 	"fmt"
 	"net"
 
-	"wisdom-gate/internal/application/protocol/consts"
-	protocolUC "wisdom-gate/internal/application/protocol/usecase"
 	quotesUC "wisdom-gate/internal/application/quotes/usecase"
 	"wisdom-gate/internal/delivery/tcp/middleware"
+	"wisdom-gate/pkg/protocol"
 )
 
 type QuotesHandler struct {
@@ -21,7 +20,7 @@ func NewQuotesHandler(quotesStore *quotesUC.QuotesUseCase) *QuotesHandler {
 	}
 }
 
-func (h *QuotesHandler) HandleQuoteRequest(ctx context.Context, conn net.Conn, clientAddr string, msg *protocolUC.Message) error {
+func (h *QuotesHandler) HandleQuoteRequest(ctx context.Context, conn net.Conn, clientAddr string, msg *protocol.Message) error {
 	verified, ok := ctx.Value(middleware.VerifiedKey).(bool)
 	if !ok || !verified {
 		return fmt.Errorf("request not verified")
@@ -29,17 +28,17 @@ func (h *QuotesHandler) HandleQuoteRequest(ctx context.Context, conn net.Conn, c
 
 	quote, err := h.quotesStore.GetRandomQuote(ctx)
 	if err != nil {
-		return err
+		return fmt.Errorf("%w: failed to get quote: %w", middleware.ErrUnavailable, err)
 	}
 
 	quoteText := fmt.Sprintf("%s — %s", quote.Text, quote.Author)
 
-	quoteMsg := &protocolUC.Message{
-		Command: consts.CmdQOT,
+	quoteMsg := &protocol.Message{
+		Command: protocol.CmdQOT,
 		Body:    quoteText,
 	}
 
-	if err := protocolUC.WriteMessage(conn, quoteMsg); err != nil {
+	if err := protocol.WriteMessage(conn, quoteMsg); err != nil {
 		return fmt.Errorf("failed to send quote: %w", err)
 	}
 
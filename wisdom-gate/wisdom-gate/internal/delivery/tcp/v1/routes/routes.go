@@ -5,35 +5,39 @@ import (
 	"fmt"
 	"net"
 
-	"wisdom-gate/internal/application/protocol/consts"
-	protocolUC "wisdom-gate/internal/application/protocol/usecase"
 	"wisdom-gate/internal/delivery/tcp/middleware"
 	"wisdom-gate/internal/delivery/tcp/v1/handlers"
+	"wisdom-gate/pkg/protocol"
 )
 
 func Route(
 	ctx context.Context,
 	conn net.Conn,
 	clientAddr string,
-	msg *protocolUC.Message,
+	msg *protocol.Message,
 	handlers handlers.Handlers,
 	middlewareChain middleware.Middleware,
 ) error {
 	var finalHandler middleware.Handler
 
 	switch msg.Command {
-	case consts.CmdREQ:
+	case protocol.CmdREQ:
 		// REQ обрабатывается в middleware (PoWChallengeMiddleware)
 		// Создаем пустой handler для middleware цепочки
-		finalHandler = func(ctx context.Context, conn net.Conn, clientAddr string, msg *protocolUC.Message) error {
+		finalHandler = func(ctx context.Context, conn net.Conn, clientAddr string, msg *protocol.Message) error {
 			return nil
 		}
-	case consts.CmdRES:
+	case protocol.CmdRES:
 		finalHandler = handlers.QuotesHandler.HandleQuoteRequest
-	case consts.CmdDISC:
+	case protocol.CmdALG:
+		finalHandler = handlers.PoWHandler.HandleAlgorithms
+	case protocol.CmdDISC:
 		finalHandler = handlers.ConnectionHandler.HandleDisconnect
 	default:
-		return fmt.Errorf("unknown client command: %s", msg.Command)
+		// Неизвестная команда тоже проходит цепочку: лимит и ответ ERR
+		finalHandler = func(ctx context.Context, conn net.Conn, clientAddr string, msg *protocol.Message) error {
+			return fmt.Errorf("%w: %s", middleware.ErrUnknownCommand, msg.Command)
+		}
 	}
 
 	handler := middlewareChain(finalHandler)
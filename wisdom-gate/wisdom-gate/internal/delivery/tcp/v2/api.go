@@ -0,0 +1,28 @@
+package v2
+
+import (
+	"context"
+	"net"
+
+	"wisdom-gate/internal/delivery/tcp/middleware"
+	"wisdom-gate/internal/delivery/tcp/v1/handlers"
+	clientRoutesV2 "wisdom-gate/internal/delivery/tcp/v2/routes"
+	"wisdom-gate/pkg/protocol"
+)
+
+// API второй версии протокола. Обработчики общие с v1, отличается маршрутизация
+type API struct {
+	handlers        handlers.Handlers
+	middlewareChain middleware.Middleware
+}
+
+func NewAPI(handlers handlers.Handlers, middlewareChain middleware.Middleware) *API {
+	return &API{
+		handlers:        handlers,
+		middlewareChain: middlewareChain,
+	}
+}
+
+func (api *API) HandleMessage(ctx context.Context, conn net.Conn, clientAddr string, msg *protocol.Message) error {
+	return clientRoutesV2.Route(ctx, conn, clientAddr, msg, api.handlers, api.middlewareChain)
+}
@@ -0,0 +1,52 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"wisdom-gate/internal/delivery/tcp/middleware"
+	"wisdom-gate/internal/delivery/tcp/v1/handlers"
+	"wisdom-gate/pkg/protocol"
+)
+
+func Route(
+	ctx context.Context,
+	conn net.Conn,
+	clientAddr string,
+	msg *protocol.Message,
+	handlers handlers.Handlers,
+	middlewareChain middleware.Middleware,
+) error {
+	var finalHandler middleware.Handler
+
+	switch msg.Command {
+	case protocol.CmdREQ:
+		// Пустой REQ выбирает из алгоритмов, согласованных в VER
+		if info, ok := ctx.Value(middleware.VersionKey).(*protocol.VersionInfo); ok && msg.Body == "" {
+			// Копия сохраняет все поля сообщения, в том числе идентификатор запроса
+			m := *msg
+			m.Body = strings.Join(info.Algorithms, protocol.AlgorithmSeparator)
+			msg = &m
+		}
+		// REQ обрабатывается в middleware (PoWChallengeMiddleware)
+		finalHandler = func(ctx context.Context, conn net.Conn, clientAddr string, msg *protocol.Message) error {
+			return nil
+		}
+	case protocol.CmdRES:
+		finalHandler = handlers.QuotesHandler.HandleQuoteRequest
+	case protocol.CmdDISC:
+		finalHandler = handlers.ConnectionHandler.HandleDisconnect
+	default:
+		// ALG в v2 не нужен: разрешённые алгоритмы уже пришли в VER.
+		// Неизвестная команда тоже проходит цепочку: лимит и ответ ERR
+		finalHandler = func(ctx context.Context, conn net.Conn, clientAddr string, msg *protocol.Message) error {
+			return fmt.Errorf("%w: %s", middleware.ErrUnknownCommand, msg.Command)
+		}
+	}
+
+	handler := middlewareChain(finalHandler)
+
+	return handler(ctx, conn, clientAddr, msg)
+}
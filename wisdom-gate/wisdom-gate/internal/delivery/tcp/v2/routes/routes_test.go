@@ -0,0 +1,36 @@
+package routes
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"wisdom-gate/internal/delivery/tcp/middleware"
+	"wisdom-gate/internal/delivery/tcp/v1/handlers"
+	"wisdom-gate/pkg/protocol"
+)
+
+func TestRoute_EmptyREQKeepsID(t *testing.T) {
+	info := &protocol.VersionInfo{Version: protocol.ProtocolV2, Algorithms: []string{protocol.AlgorithmSha256}}
+	ctx := context.WithValue(context.Background(), middleware.VersionKey, info)
+
+	var got *protocol.Message
+	capture := func(next middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, conn net.Conn, clientAddr string, msg *protocol.Message) error {
+			got = msg
+			return next(ctx, conn, clientAddr, msg)
+		}
+	}
+
+	msg := &protocol.Message{Command: protocol.CmdREQ, ID: "42"}
+	if err := Route(ctx, nil, "127.0.0.1", msg, handlers.Handlers{}, capture); err != nil {
+		t.Fatalf("Route() error = %v", err)
+	}
+
+	if got.ID != "42" || got.Body != protocol.AlgorithmSha256 {
+		t.Errorf("routed message = %+v, want ID 42 with negotiated algorithms", got)
+	}
+	if msg.Body != "" {
+		t.Errorf("original message body = %q, want unchanged", msg.Body)
+	}
+}
@@ -0,0 +1,355 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"wisdom-gate/pkg/protocol"
+)
+
+// Client получает цитаты с сервера wisdom-gate: запрашивает challenge,
+// решает его и отправляет решение. Соединение переиспользуется между
+// запросами и переустанавливается после сетевой ошибки. Вызовы GetQuote
+// безопасны для конкурентного использования, но выполняются по очереди.
+type Client struct {
+	addr string
+	opts Options
+
+	mu      sync.Mutex
+	conn    *protocol.Conn
+	version *protocol.VersionInfo
+	closed  bool
+
+	jitter func(int64) int64
+	sleep  func(ctx context.Context, d time.Duration) error
+}
+
+func New(addr string, opts Options) *Client {
+	return &Client{
+		addr:   addr,
+		opts:   opts.withDefaults(),
+		jitter: rand.Int64N,
+		sleep:  sleepContext,
+	}
+}
+
+// Dial создаёт клиент и сразу устанавливает соединение
+func Dial(ctx context.Context, addr string, opts Options) (*Client, error) {
+	c := New(addr, opts)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.connect(ctx); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// GetQuote возвращает цитату. На повторяемый ERR от сервера, в том числе об
+// истёкшем challenge, и на сетевые ошибки запрос повторяется с экспоненциальной
+// паузой, не меньшей подсказки retry_after
+func (c *Client) GetQuote(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		if c.closed {
+			return "", ErrClosed
+		}
+
+		var quote string
+		quote, err = c.getQuote(ctx)
+		if err == nil {
+			return quote, nil
+		}
+
+		if attempt >= c.opts.MaxRetries || !retryable(err) || ctx.Err() != nil {
+			break
+		}
+
+		if sleepErr := c.sleep(ctx, c.pause(attempt, err)); sleepErr != nil {
+			break
+		}
+	}
+
+	return "", err
+}
+
+// Version возвращает параметры, согласованные с сервером через HELLO/VER
+// на текущем соединении, или nil без рукопожатия
+func (c *Client) Version() *protocol.VersionInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.version
+}
+
+// Close сообщает серверу об отключении и закрывает соединение
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+
+	if c.conn == nil {
+		return nil
+	}
+
+	_ = c.conn.SetWriteDeadline(time.Now().Add(c.opts.DialTimeout))
+	_ = protocol.WriteMessage(c.conn, &protocol.Message{Command: protocol.CmdDISC})
+
+	return c.disconnect()
+}
+
+// Ping проверяет соединение и продлевает его на сервере, сбрасывая таймер простоя.
+// Без открытого соединения устанавливает новое
+func (c *Client) Ping(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return ErrClosed
+	}
+
+	_, err := c.exchange(ctx, func(ctx context.Context) (string, error) {
+		if err := protocol.WriteMessage(c.conn, &protocol.Message{Command: protocol.CmdPING}); err != nil {
+			return "", fmt.Errorf("failed to send ping: %w", err)
+		}
+		return c.expect(protocol.CmdPONG)
+	})
+
+	return err
+}
+
+// exchange выполняет обмен по соединению, при необходимости устанавливая его
+func (c *Client) exchange(ctx context.Context, fn func(ctx context.Context) (string, error)) (body string, err error) {
+	if c.conn == nil {
+		if err := c.connect(ctx); err != nil {
+			return "", err
+		}
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = c.conn.SetDeadline(deadline)
+	}
+	// Отмена контекста прерывает блокирующие чтение и запись
+	conn := c.conn
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	defer func() {
+		if !stop() || !keepConn(err) {
+			// Состояние обмена неизвестно, дальше соединением пользоваться нельзя
+			_ = c.disconnect()
+			if ctxErr := ctx.Err(); ctxErr != nil && isNetError(err) {
+				err = ctxErr
+			}
+			return
+		}
+		_ = c.conn.SetDeadline(time.Time{})
+	}()
+
+	return fn(ctx)
+}
+
+func (c *Client) getQuote(ctx context.Context) (string, error) {
+	return c.exchange(ctx, c.requestQuote)
+}
+
+func (c *Client) requestQuote(ctx context.Context) (string, error) {
+	// По v2 алгоритмы уже согласованы в VER
+	request := &protocol.Message{Command: protocol.CmdREQ, Body: protocol.SupportedAlgorithms()}
+	if c.version != nil && c.version.Version >= protocol.ProtocolV2 {
+		request.Body = ""
+	}
+
+	if err := protocol.WriteMessage(c.conn, request); err != nil {
+		return "", fmt.Errorf("failed to request challenge: %w", err)
+	}
+
+	challenge, err := c.expect(protocol.CmdCHL)
+	if err != nil {
+		return "", err
+	}
+
+	solution, err := c.solve(ctx, challenge)
+	if err != nil {
+		return "", err
+	}
+
+	if err := protocol.WriteMessage(c.conn, &protocol.Message{
+		Command: protocol.CmdRES,
+		Body:    solution,
+	}); err != nil {
+		return "", fmt.Errorf("failed to send solution: %w", err)
+	}
+
+	return c.expect(protocol.CmdQOT)
+}
+
+// expect читает ответ и возвращает его тело, если команда совпала
+func (c *Client) expect(command string) (string, error) {
+	msg, err := c.conn.ReadMessage()
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", command, err)
+	}
+
+	switch msg.Command {
+	case command:
+		return msg.Body, nil
+	case protocol.CmdERR:
+		return "", newServerError(msg.Body)
+	case protocol.CmdDISC:
+		// Сервер закрыл соединение по простою, времени жизни или при остановке
+		return "", fmt.Errorf("%w: %s", ErrDisconnected, msg.Body)
+	default:
+		return "", fmt.Errorf("%w: expected %s, got %s", ErrUnexpectedResponse, command, msg.Command)
+	}
+}
+
+func (c *Client) solve(ctx context.Context, challenge string) (string, error) {
+	// В режиме hashcash сервер присылает шаблон марки Hashcash v1
+	if protocol.IsStampTemplate(challenge) {
+		template, err := protocol.ParseHashcashStamp(challenge)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse stamp: %w", err)
+		}
+
+		stamp, err := protocol.SolveStamp(ctx, template)
+		if err != nil {
+			return "", fmt.Errorf("failed to mint stamp: %w", err)
+		}
+
+		return stamp.String(), nil
+	}
+
+	header, err := protocol.ParseHashcashHeader(challenge)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse challenge: %w", err)
+	}
+
+	solution, err := protocol.Solve(ctx, header, c.opts.Workers)
+	if err != nil {
+		return "", fmt.Errorf("failed to solve challenge: %w", err)
+	}
+
+	return solution.String(), nil
+}
+
+func (c *Client) connect(ctx context.Context) error {
+	dialCtx, cancel := context.WithTimeout(ctx, c.opts.DialTimeout)
+	defer cancel()
+
+	conn, err := c.opts.Dial(dialCtx, "tcp", c.addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", c.addr, err)
+	}
+
+	c.conn = protocol.NewClientConn(conn, c.opts.Framing, c.opts.MaxFrameSize)
+	c.version = nil
+
+	if c.opts.NoHandshake {
+		return nil
+	}
+
+	if err := c.handshake(dialCtx); err != nil {
+		_ = c.disconnect()
+		return fmt.Errorf("handshake failed: %w", err)
+	}
+
+	return nil
+}
+
+// handshake предлагает серверу версии протокола и алгоритмы решателя
+func (c *Client) handshake(ctx context.Context) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = c.conn.SetDeadline(deadline)
+		defer func() { _ = c.conn.SetDeadline(time.Time{}) }()
+	}
+
+	hello := &protocol.Hello{
+		Versions:   []int{protocol.ProtocolV1, protocol.ProtocolV2},
+		Algorithms: strings.Split(protocol.SupportedAlgorithms(), protocol.AlgorithmSeparator),
+	}
+	if err := protocol.WriteMessage(c.conn, &protocol.Message{Command: protocol.CmdHELLO, Body: hello.String()}); err != nil {
+		return err
+	}
+
+	body, err := c.expect(protocol.CmdVER)
+	if err != nil {
+		return err
+	}
+
+	if c.version, err = protocol.ParseVersionInfo(body); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *Client) disconnect() error {
+	if c.conn == nil {
+		return nil
+	}
+
+	err := c.conn.Close()
+	c.conn = nil
+	c.version = nil
+
+	return err
+}
+
+// keepConn сообщает, можно ли продолжать обмен по соединению после ошибки:
+// после ERR сервер ждёт следующего запроса, после остальных ошибок поток рассинхронизирован
+func keepConn(err error) bool {
+	var serverErr *ServerError
+	return err == nil || errors.As(err, &serverErr)
+}
+
+func retryable(err error) bool {
+	var serverErr *ServerError
+	if errors.As(err, &serverErr) {
+		return serverErr.retryable()
+	}
+	return isNetError(err) || errors.Is(err, ErrDisconnected)
+}
+
+// pause — экспоненциальная пауза, но не меньше подсказки сервера
+func (c *Client) pause(attempt int, err error) time.Duration {
+	d := c.opts.backoff(attempt, c.jitter)
+
+	var serverErr *ServerError
+	if errors.As(err, &serverErr) && serverErr.RetryAfter > d {
+		d = serverErr.RetryAfter
+	}
+
+	return d
+}
+
+func isNetError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,498 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"wisdom-gate/pkg/protocol"
+)
+
+// fakeServer выдаёт challenge на каждый REQ, а ответ на REQ и RES
+// определяют функции теста; n — порядковый номер сообщения этого типа
+type fakeServer struct {
+	ln       net.Listener
+	dials    atomic.Int32
+	hellos   atomic.Int32
+	requests atomic.Int32
+	answers  atomic.Int32
+	lastREQ  atomic.Value
+
+	onREQ func(n int) *protocol.Message
+	onRES func(n int) *protocol.Message
+}
+
+func newFakeServer(t *testing.T, onREQ, onRES func(n int) *protocol.Message) *fakeServer {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	s := &fakeServer{ln: ln, onREQ: onREQ, onRES: onRES}
+	go s.serve()
+
+	return s
+}
+
+func (s *fakeServer) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.dials.Add(1)
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeServer) handle(netConn net.Conn) {
+	conn := protocol.NewServerConn(netConn, 0)
+	defer conn.Close()
+
+	for {
+		msg, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var reply *protocol.Message
+		switch msg.Command {
+		case protocol.CmdHELLO:
+			s.hellos.Add(1)
+			info := &protocol.VersionInfo{
+				Version:      protocol.ProtocolV2,
+				Algorithms:   []string{protocol.AlgorithmSha256},
+				Difficulty:   4,
+				MaxFrameSize: protocol.DefaultMaxFrameSize,
+			}
+			reply = &protocol.Message{Command: protocol.CmdVER, Body: info.String()}
+		case protocol.CmdREQ:
+			s.lastREQ.Store(msg.Body)
+			n := int(s.requests.Add(1))
+			if s.onREQ != nil {
+				reply = s.onREQ(n)
+			}
+			if reply == nil {
+				reply = &protocol.Message{Command: protocol.CmdCHL, Body: testChallenge().String()}
+			}
+		case protocol.CmdRES:
+			if !validSolution(msg.Body) {
+				reply = &protocol.Message{Command: protocol.CmdERR, Body: "ERROR: insufficient proof of work"}
+				break
+			}
+			n := int(s.answers.Add(1))
+			if s.onRES != nil {
+				reply = s.onRES(n)
+			}
+			if reply == nil {
+				reply = &protocol.Message{Command: protocol.CmdQOT, Body: "quote"}
+			}
+		case protocol.CmdPING:
+			reply = &protocol.Message{Command: protocol.CmdPONG, Body: msg.Body}
+		case protocol.CmdDISC:
+			return
+		default:
+			continue
+		}
+
+		if reply.Command == "" {
+			// Пустая команда — закрыть соединение без ответа
+			return
+		}
+		if err := protocol.WriteMessage(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+func testChallenge() *protocol.HashcashHeader {
+	return &protocol.HashcashHeader{
+		Version:    protocol.HeaderVersionEncoded,
+		Difficulty: 4,
+		ExpiresAt:  time.Now().Add(time.Minute).Unix(),
+		Subject:    "127.0.0.1",
+		Algorithm:  protocol.AlgorithmSha256,
+		Nonce:      "nonce",
+	}
+}
+
+func validSolution(solution string) bool {
+	header, err := protocol.ParseHashcashHeader(solution)
+	if err != nil {
+		return false
+	}
+
+	sum, err := protocol.Hasher(header.Algorithm)
+	if err != nil {
+		return false
+	}
+
+	ok, err := protocol.MeetsDifficulty(sum([]byte(solution)), header.Version, header.Difficulty)
+	return err == nil && ok
+}
+
+func newTestClient(t *testing.T, s *fakeServer, opts Options) (*Client, *[]time.Duration) {
+	t.Helper()
+
+	c := New(s.ln.Addr().String(), opts)
+	t.Cleanup(func() { _ = c.Close() })
+
+	// Паузы между повторами только записываются, чтобы тесты не ждали
+	var pauses []time.Duration
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		pauses = append(pauses, d)
+		return ctx.Err()
+	}
+
+	return c, &pauses
+}
+
+func TestClient_GetQuote_ReusesConnection(t *testing.T) {
+	for _, framing := range []int{protocol.FramingV1, protocol.FramingV2} {
+		t.Run(fmt.Sprintf("framing v%d", framing), func(t *testing.T) {
+			s := newFakeServer(t, nil, nil)
+			c, _ := newTestClient(t, s, Options{Workers: 2, Framing: framing})
+
+			for range 3 {
+				quote, err := c.GetQuote(context.Background())
+				if err != nil {
+					t.Fatalf("GetQuote() error = %v", err)
+				}
+				if quote != "quote" {
+					t.Errorf("GetQuote() = %v, want quote", quote)
+				}
+			}
+
+			if got := s.dials.Load(); got != 1 {
+				t.Errorf("dials = %d, want 1", got)
+			}
+		})
+	}
+}
+
+func TestClient_Ping(t *testing.T) {
+	s := newFakeServer(t, nil, nil)
+	c, _ := newTestClient(t, s, Options{Workers: 2})
+
+	for range 2 {
+		if err := c.Ping(context.Background()); err != nil {
+			t.Fatalf("Ping() error = %v", err)
+		}
+	}
+	if _, err := c.GetQuote(context.Background()); err != nil {
+		t.Fatalf("GetQuote() error = %v", err)
+	}
+
+	if got := s.dials.Load(); got != 1 {
+		t.Errorf("dials = %d, want 1", got)
+	}
+}
+
+func TestClient_Handshake(t *testing.T) {
+	tests := []struct {
+		name        string
+		noHandshake bool
+		wantHellos  int32
+		wantVersion int
+		wantREQ     string
+	}{
+		{
+			name:        "negotiates v2",
+			wantHellos:  1,
+			wantVersion: protocol.ProtocolV2,
+			wantREQ:     "",
+		},
+		{
+			name:        "legacy server",
+			noHandshake: true,
+			wantHellos:  0,
+			wantREQ:     protocol.SupportedAlgorithms(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newFakeServer(t, nil, nil)
+
+			c, err := Dial(context.Background(), s.ln.Addr().String(), Options{Workers: 2, NoHandshake: tt.noHandshake})
+			if err != nil {
+				t.Fatalf("Dial() error = %v", err)
+			}
+			defer c.Close()
+
+			if _, err := c.GetQuote(context.Background()); err != nil {
+				t.Fatalf("GetQuote() error = %v", err)
+			}
+
+			if got := s.hellos.Load(); got != tt.wantHellos {
+				t.Errorf("hellos = %d, want %d", got, tt.wantHellos)
+			}
+			if got := s.lastREQ.Load(); got != tt.wantREQ {
+				t.Errorf("REQ body = %q, want %q", got, tt.wantREQ)
+			}
+
+			version := 0
+			if info := c.Version(); info != nil {
+				version = info.Version
+			}
+			if version != tt.wantVersion {
+				t.Errorf("Version() = %d, want %d", version, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestClient_GetQuote_Retries(t *testing.T) {
+	tests := []struct {
+		name        string
+		onREQ       func(n int) *protocol.Message
+		onRES       func(n int) *protocol.Message
+		wantDials   int32
+		wantRetries int
+	}{
+		{
+			name: "expired challenge",
+			onRES: func(n int) *protocol.Message {
+				if n == 1 {
+					return &protocol.Message{Command: protocol.CmdERR, Body: "ERROR: challenge expired"}
+				}
+				return nil
+			},
+			wantDials:   1,
+			wantRetries: 1,
+		},
+		{
+			name: "rate limited",
+			onREQ: func(n int) *protocol.Message {
+				if n <= 2 {
+					return &protocol.Message{Command: protocol.CmdERR, Body: "ERROR: rate limit exceeded"}
+				}
+				return nil
+			},
+			wantDials:   1,
+			wantRetries: 2,
+		},
+		{
+			name: "connection dropped",
+			onRES: func(n int) *protocol.Message {
+				if n == 1 {
+					return &protocol.Message{}
+				}
+				return nil
+			},
+			wantDials:   2,
+			wantRetries: 1,
+		},
+		{
+			name: "disconnected by server",
+			onREQ: func(n int) *protocol.Message {
+				if n == 1 {
+					return &protocol.Message{Command: protocol.CmdDISC, Body: protocol.CloseReasonIdle}
+				}
+				return nil
+			},
+			wantDials:   2,
+			wantRetries: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newFakeServer(t, tt.onREQ, tt.onRES)
+			c, pauses := newTestClient(t, s, Options{Workers: 2})
+
+			quote, err := c.GetQuote(context.Background())
+			if err != nil {
+				t.Fatalf("GetQuote() error = %v", err)
+			}
+			if quote != "quote" {
+				t.Errorf("GetQuote() = %v, want quote", quote)
+			}
+			if got := s.dials.Load(); got != tt.wantDials {
+				t.Errorf("dials = %d, want %d", got, tt.wantDials)
+			}
+			if len(*pauses) != tt.wantRetries {
+				t.Errorf("retries = %d, want %d", len(*pauses), tt.wantRetries)
+			}
+		})
+	}
+}
+
+func TestClient_GetQuote_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		onREQ       func(n int) *protocol.Message
+		onRES       func(n int) *protocol.Message
+		wantErr     error
+		wantRetries int
+	}{
+		{
+			name: "rate limit persists",
+			onREQ: func(int) *protocol.Message {
+				return &protocol.Message{Command: protocol.CmdERR, Body: "ERROR: rate limit exceeded"}
+			},
+			wantErr:     ErrRateLimited,
+			wantRetries: 2,
+		},
+		{
+			name: "challenge keeps expiring",
+			onRES: func(int) *protocol.Message {
+				return &protocol.Message{Command: protocol.CmdERR, Body: "ERROR: challenge not found"}
+			},
+			wantErr:     ErrChallengeExpired,
+			wantRetries: 2,
+		},
+		{
+			name: "coded error not retryable",
+			onRES: func(int) *protocol.Message {
+				return &protocol.Message{Command: protocol.CmdERR, Body: "code=POW_INVALID;retryable=false;message=invalid proof of work"}
+			},
+			wantErr:     ErrServer,
+			wantRetries: 0,
+		},
+		{
+			name: "coded unavailable retried",
+			onRES: func(int) *protocol.Message {
+				return &protocol.Message{Command: protocol.CmdERR, Body: "code=UNAVAILABLE;retryable=true;message=service temporarily unavailable"}
+			},
+			wantErr:     ErrUnavailable,
+			wantRetries: 2,
+		},
+		{
+			name: "server busy",
+			onREQ: func(int) *protocol.Message {
+				return &protocol.Message{Command: protocol.CmdERR, Body: "code=BUSY;retryable=true;retry_after=1000;message=server busy"}
+			},
+			wantErr:     ErrBusy,
+			wantRetries: 2,
+		},
+		{
+			name: "unexpected command",
+			onRES: func(int) *protocol.Message {
+				return &protocol.Message{Command: protocol.CmdCHL, Body: "challenge"}
+			},
+			wantErr:     ErrUnexpectedResponse,
+			wantRetries: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newFakeServer(t, tt.onREQ, tt.onRES)
+			c, pauses := newTestClient(t, s, Options{Workers: 2, MaxRetries: 2})
+
+			_, err := c.GetQuote(context.Background())
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetQuote() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(*pauses) != tt.wantRetries {
+				t.Errorf("retries = %d, want %d", len(*pauses), tt.wantRetries)
+			}
+		})
+	}
+}
+
+func TestClient_GetQuote_RetryAfter(t *testing.T) {
+	s := newFakeServer(t, func(n int) *protocol.Message {
+		if n == 1 {
+			resp := &protocol.ErrorResponse{Code: protocol.CodeRateLimited, Message: "rate limit exceeded", Retryable: true, RetryAfter: 5 * time.Second}
+			return &protocol.Message{Command: protocol.CmdERR, Body: resp.String()}
+		}
+		return nil
+	}, nil)
+	c, pauses := newTestClient(t, s, Options{Workers: 2})
+
+	if _, err := c.GetQuote(context.Background()); err != nil {
+		t.Fatalf("GetQuote() error = %v", err)
+	}
+	if len(*pauses) != 1 || (*pauses)[0] != 5*time.Second {
+		t.Errorf("pauses = %v, want [5s]", *pauses)
+	}
+}
+
+func TestClient_GetQuote_ServerError(t *testing.T) {
+	s := newFakeServer(t, nil, func(int) *protocol.Message {
+		return &protocol.Message{Command: protocol.CmdERR, Body: "ERROR: insufficient proof of work"}
+	})
+	c, _ := newTestClient(t, s, Options{MaxRetries: -1})
+
+	_, err := c.GetQuote(context.Background())
+
+	var serverErr *ServerError
+	if !errors.As(err, &serverErr) {
+		t.Fatalf("GetQuote() error = %v, want *ServerError", err)
+	}
+	if serverErr.Message != "insufficient proof of work" {
+		t.Errorf("ServerError.Message = %q, want %q", serverErr.Message, "insufficient proof of work")
+	}
+	if errors.Is(err, ErrChallengeExpired) || errors.Is(err, ErrRateLimited) {
+		t.Errorf("GetQuote() error = %v matches a specific sentinel", err)
+	}
+}
+
+func TestClient_GetQuote_ContextCancelled(t *testing.T) {
+	block := make(chan struct{})
+	s := newFakeServer(t, nil, func(int) *protocol.Message {
+		<-block
+		return nil
+	})
+	t.Cleanup(func() { close(block) })
+	c, _ := newTestClient(t, s, Options{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := c.GetQuote(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetQuote() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if c.conn != nil {
+		t.Error("connection kept after cancelled exchange")
+	}
+}
+
+func TestClient_Closed(t *testing.T) {
+	s := newFakeServer(t, nil, nil)
+
+	c, err := Dial(context.Background(), s.ln.Addr().String(), Options{})
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	if _, err := c.GetQuote(context.Background()); !errors.Is(err, ErrClosed) {
+		t.Errorf("GetQuote() after Close error = %v, want %v", err, ErrClosed)
+	}
+}
+
+func TestOptions_Backoff(t *testing.T) {
+	opts := Options{BaseBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}.withDefaults()
+	noJitter := func(int64) int64 { return 0 }
+	fullJitter := func(n int64) int64 { return n - 1 }
+
+	tests := []struct {
+		attempt int
+		jitter  func(int64) int64
+		want    time.Duration
+	}{
+		{attempt: 0, jitter: noJitter, want: 50 * time.Millisecond},
+		{attempt: 0, jitter: fullJitter, want: 100 * time.Millisecond},
+		{attempt: 2, jitter: fullJitter, want: 400 * time.Millisecond},
+		{attempt: 10, jitter: fullJitter, want: time.Second},
+		{attempt: 10, jitter: noJitter, want: 500 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		if got := opts.backoff(tt.attempt, tt.jitter); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package client
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"wisdom-gate/pkg/protocol"
+)
+
+var (
+	ErrClosed             = errors.New("client closed")
+	ErrServer             = errors.New("server error")
+	ErrRateLimited        = errors.New("rate limited")
+	ErrChallengeExpired   = errors.New("challenge expired")
+	ErrUnavailable        = errors.New("server unavailable")
+	ErrBusy               = errors.New("server busy")
+	ErrUnexpectedResponse = errors.New("unexpected response")
+	// ErrDisconnected — сервер закрыл соединение, причина идёт после двоеточия
+	ErrDisconnected = errors.New("disconnected by server")
+)
+
+// ServerError — ответ ERR от сервера. errors.Is сопоставляет его с ErrServer,
+// а по коду ещё и с ErrRateLimited, ErrChallengeExpired, ErrUnavailable или ErrBusy.
+// Code пуст, если сервер прислал ошибку старого формата без кода
+type ServerError struct {
+	Code       protocol.ErrorCode
+	Message    string
+	Retryable  bool
+	RetryAfter time.Duration
+}
+
+func (e *ServerError) Error() string {
+	if e.Code == "" {
+		return "server error: " + e.Message
+	}
+	return "server error " + string(e.Code) + ": " + e.Message
+}
+
+func (e *ServerError) Is(target error) bool {
+	switch target {
+	case ErrServer:
+		return true
+	case ErrRateLimited:
+		return e.Code == protocol.CodeRateLimited ||
+			e.Code == "" && strings.Contains(e.Message, "rate limit exceeded")
+	case ErrChallengeExpired:
+		// Challenge с истёкшим TTL в stored-режиме старый сервер уже не находит
+		return e.Code == protocol.CodePoWExpired ||
+			e.Code == "" && (strings.Contains(e.Message, "challenge expired") ||
+				strings.Contains(e.Message, "stamp expired") ||
+				strings.Contains(e.Message, "challenge not found"))
+	case ErrUnavailable:
+		return e.Code == protocol.CodeUnavailable
+	case ErrBusy:
+		return e.Code == protocol.CodeBusy
+	}
+	return false
+}
+
+// retryable: ошибки с кодом повторяются по флагу сервера, старого формата — всегда
+func (e *ServerError) retryable() bool {
+	return e.Code == "" || e.Retryable
+}
+
+func newServerError(body string) *ServerError {
+	resp := protocol.ParseErrorResponse(body)
+
+	return &ServerError{
+		Code:       resp.Code,
+		Message:    resp.Message,
+		Retryable:  resp.Retryable,
+		RetryAfter: resp.RetryAfter,
+	}
+}
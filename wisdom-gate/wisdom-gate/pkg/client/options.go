@@ -0,0 +1,82 @@
+package client
+
+import (
+	"context"
+	"net"
+	"runtime"
+	"time"
+
+	"wisdom-gate/pkg/protocol"
+)
+
+const (
+	DefaultMaxRetries  = 3
+	DefaultBaseBackoff = 200 * time.Millisecond
+	DefaultMaxBackoff  = 5 * time.Second
+	DefaultDialTimeout = 5 * time.Second
+)
+
+// DialFunc устанавливает соединение с сервером, по умолчанию net.Dialer
+type DialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// Options — настройки клиента, нулевые значения заменяются умолчаниями
+type Options struct {
+	Workers     int           // горутин подбора решения, по умолчанию runtime.NumCPU()
+	MaxRetries  int           // повторов после первой попытки; отрицательное значение отключает повторы
+	BaseBackoff time.Duration // пауза перед первым повтором, далее удваивается
+	MaxBackoff  time.Duration
+	DialTimeout time.Duration
+	Dial        DialFunc
+	// Framing — формат кадров: protocol.FramingV2 по умолчанию или FramingV1 для старых серверов
+	Framing      int
+	MaxFrameSize int
+	// NoHandshake отключает HELLO/VER для серверов, которые его не знают
+	NoHandshake bool
+}
+
+func (o Options) withDefaults() Options {
+	if o.Workers <= 0 {
+		o.Workers = runtime.NumCPU()
+	}
+	if o.MaxRetries == 0 {
+		o.MaxRetries = DefaultMaxRetries
+	}
+	if o.MaxRetries < 0 {
+		o.MaxRetries = 0
+	}
+	if o.BaseBackoff <= 0 {
+		o.BaseBackoff = DefaultBaseBackoff
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = DefaultMaxBackoff
+	}
+	if o.DialTimeout <= 0 {
+		o.DialTimeout = DefaultDialTimeout
+	}
+	if o.Framing == 0 {
+		o.Framing = protocol.FramingV2
+	}
+	if o.MaxFrameSize <= 0 {
+		o.MaxFrameSize = protocol.DefaultMaxFrameSize
+	}
+	if o.Dial == nil {
+		o.Dial = (&net.Dialer{}).DialContext
+	}
+	return o
+}
+
+// backoff возвращает паузу перед повтором attempt (с нуля) с джиттером до половины
+func (o Options) backoff(attempt int, jitter func(int64) int64) time.Duration {
+	d := o.BaseBackoff
+	for range attempt {
+		d *= 2
+		if d >= o.MaxBackoff {
+			d = o.MaxBackoff
+			break
+		}
+	}
+	if half := int64(d / 2); half > 0 {
+		d = d/2 + time.Duration(jitter(half+1))
+	}
+	return d
+}
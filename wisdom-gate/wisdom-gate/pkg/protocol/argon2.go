@@ -0,0 +1,63 @@
+package protocol
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Argon2Salt — фиксированная соль Argon2id, общая для сервера и клиентов
+var Argon2Salt = []byte("wisdom-gate/pow")
+
+// Argon2KeyLen — длина дайджеста Argon2id в байтах
+const Argon2KeyLen = 32
+
+// Argon2Params — параметры Argon2id. Memory задаётся в KiB.
+type Argon2Params struct {
+	Memory  uint32
+	Time    uint32
+	Threads uint8
+}
+
+func (p Argon2Params) String() string {
+	return fmt.Sprintf("m=%d,t=%d,p=%d", p.Memory, p.Time, p.Threads)
+}
+
+// ParseArgon2Params разбирает параметры вида "m=8192,t=1,p=1"
+func ParseArgon2Params(s string) (Argon2Params, error) {
+	var p Argon2Params
+	seen := make(map[string]bool, 3)
+
+	for _, field := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok || seen[key] {
+			return Argon2Params{}, fmt.Errorf("invalid argon2 params: %q", s)
+		}
+		seen[key] = true
+
+		n, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return Argon2Params{}, fmt.Errorf("invalid argon2 param %s: %w", key, err)
+		}
+
+		switch key {
+		case "m":
+			p.Memory = uint32(n)
+		case "t":
+			p.Time = uint32(n)
+		case "p":
+			if n > 255 {
+				return Argon2Params{}, fmt.Errorf("invalid argon2 param p: %d", n)
+			}
+			p.Threads = uint8(n)
+		default:
+			return Argon2Params{}, fmt.Errorf("unknown argon2 param: %s", key)
+		}
+	}
+
+	if len(seen) != 3 || p.Memory == 0 || p.Time == 0 || p.Threads == 0 {
+		return Argon2Params{}, fmt.Errorf("invalid argon2 params: %q", s)
+	}
+
+	return p, nil
+}
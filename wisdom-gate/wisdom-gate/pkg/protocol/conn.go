@@ -0,0 +1,103 @@
+package protocol
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"sync"
+)
+
+// lineOverhead — запас строки v1 на команду, длину тела и разделители
+const lineOverhead = 64
+
+var _ MessageWriter = (*Conn)(nil)
+
+// Conn — соединение, читающее и пишущее сообщения в своём формате кадров.
+// Conn остаётся net.Conn, поэтому его можно передать в WriteMessage и
+// обработчики как обычное соединение
+type Conn struct {
+	net.Conn
+
+	reader       *bufio.Reader
+	frames       *FrameReader
+	maxFrameSize int
+	framing      int
+	// client — соединение клиента: сервер, ещё не узнавший его формат, отвечает текстом v1
+	client bool
+
+	// wmu не даёт перемешаться ответам, которые пишутся из разных горутин
+	wmu sync.Mutex
+}
+
+// NewServerConn определяет формат кадров по первому байту, присланному клиентом
+func NewServerConn(conn net.Conn, maxFrameSize int) *Conn {
+	return newConn(conn, 0, maxFrameSize, false)
+}
+
+// NewClientConn использует заданный формат кадров с первого сообщения
+func NewClientConn(conn net.Conn, framing, maxFrameSize int) *Conn {
+	return newConn(conn, framing, maxFrameSize, true)
+}
+
+func newConn(conn net.Conn, framing, maxFrameSize int, client bool) *Conn {
+	if maxFrameSize <= 0 {
+		maxFrameSize = DefaultMaxFrameSize
+	}
+
+	reader := bufio.NewReader(conn)
+
+	return &Conn{
+		Conn:         conn,
+		reader:       reader,
+		frames:       NewFrameReader(reader, maxFrameSize),
+		maxFrameSize: maxFrameSize,
+		framing:      framing,
+		client:       client,
+	}
+}
+
+// Framing возвращает формат кадров соединения, 0 — ещё не определён
+func (c *Conn) Framing() int {
+	return c.framing
+}
+
+func (c *Conn) ReadMessage() (*Message, error) {
+	if c.framing == 0 {
+		first, err := c.reader.Peek(1)
+		if err != nil {
+			return nil, err
+		}
+		c.framing = FramingV1
+		if first[0] == FrameMagic {
+			c.framing = FramingV2
+		}
+	}
+
+	switch c.framing {
+	case FramingV2:
+		// Отказ до первого сообщения или DISC молчащему клиенту приходят текстом v1
+		if c.client {
+			if first, err := c.reader.Peek(1); err == nil && first[0] != FrameMagic {
+				return readLineMessage(c.reader, c.maxFrameSize+lineOverhead)
+			}
+		}
+		return c.frames.ReadMessage()
+	case FramingV1:
+		return readLineMessage(c.reader, c.maxFrameSize+lineOverhead)
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedFrameVersion, c.framing)
+	}
+}
+
+func (c *Conn) WriteMessage(msg *Message) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	switch c.framing {
+	case FramingV2:
+		return WriteFrame(c.Conn, msg.token(), []byte(msg.Body))
+	default:
+		// До первого сообщения клиента формат неизвестен, отвечаем текстом v1
+		return WriteMessage(c.Conn, msg)
+	}
+}
@@ -0,0 +1,92 @@
+package protocol
+
+const (
+	CmdREQ  = "REQ"
+	CmdRES  = "RES"
+	CmdCHL  = "CHL"
+	CmdERR  = "ERR"
+	CmdDISC = "DISC"
+	CmdQOT  = "QOT"
+	CmdALG  = "ALG"
+	// CmdHELLO открывает соединение: клиент перечисляет версии протокола и алгоритмы
+	CmdHELLO = "HELLO"
+	// CmdVER — ответ на HELLO с выбранной версией и ограничениями сервера
+	CmdVER = "VER"
+	// CmdPING — проверка соединения, сервер отвечает CmdPONG с тем же телом
+	CmdPING = "PING"
+	CmdPONG = "PONG"
+)
+
+// Причины закрытия соединения. Закрывая соединение сам, сервер присылает
+// причину в теле DISC
+const (
+	CloseReasonClient   = "client_closed"
+	CloseReasonIdle     = "idle_timeout"
+	CloseReasonLifetime = "lifetime_exceeded"
+	CloseReasonShutdown = "server_shutdown"
+	CloseReasonProtocol = "protocol_error"
+	// CloseReasonSlowClient — клиент передаёт или читает данные слишком медленно
+	CloseReasonSlowClient = "slow_client"
+	CloseReasonError      = "io_error"
+)
+
+// Версии прикладного протокола, согласуемые через HELLO/VER.
+// Клиент без HELLO работает по ProtocolV1
+const (
+	ProtocolV1 = 1
+	// ProtocolV2 — алгоритмы согласованы в VER: пустой REQ выбирает из них, ALG не нужен
+	ProtocolV2 = 2
+)
+
+const (
+	AlgorithmSha256 = "sha-256"
+	AlgorithmSha512 = "sha-512"
+	// AlgorithmBlake2b — BLAKE2b с 256-битным дайджестом
+	AlgorithmBlake2b = "blake2b"
+	AlgorithmSha3256 = "sha3-256"
+	// AlgorithmArgon2id — memory-hard алгоритм, параметры идут после AlgorithmParamsSeparator
+	AlgorithmArgon2id = "argon2id"
+)
+
+// AlgorithmParamsSeparator отделяет имя алгоритма от его параметров: "argon2id$m=8192,t=1,p=1"
+const AlgorithmParamsSeparator = "$"
+
+// AlgorithmSeparator разделяет список алгоритмов в REQ и ALG
+const AlgorithmSeparator = ","
+
+// Версия заголовка определяет смысл поля Difficulty
+const (
+	// HeaderVersionHex — число ведущих нулевых hex-символов дайджеста
+	HeaderVersionHex = 1
+	// HeaderVersionBits — число ведущих нулевых бит дайджеста
+	HeaderVersionBits = 2
+	// HeaderVersionEncoded — сложность в битах, subject и nonce в base64url без
+	// выравнивания, поэтому заголовок однозначно разбирается при любом subject
+	HeaderVersionEncoded = 3
+)
+
+// HashcashStampVersion — версия формата марок hashcash.org, которую понимают
+// стандартные утилиты: ver:bits:date:resource:ext:rand:counter
+const HashcashStampVersion = 1
+
+// RequestIDSeparator отделяет необязательный идентификатор запроса от команды: "REQ#42".
+// Идентификатор — до MaxRequestIDLength символов [A-Za-z0-9_-]
+const RequestIDSeparator = "#"
+
+const MaxRequestIDLength = 32
+
+// Формат кадров соединения
+const (
+	// FramingV1 — текстовые строки "CMD <len> |<body>\n"
+	FramingV1 = 1
+	// FramingV2 — двоичные кадры: FrameMagic, версия, длина и имя команды,
+	// uint32 big-endian длина тела и само тело
+	FramingV2 = 2
+)
+
+// FrameMagic открывает каждый кадр v2. Текстовая команда v1 не может начинаться
+// с этого байта, поэтому формат соединения определяется по первому байту
+const FrameMagic byte = 0xB7
+
+// DefaultMaxFrameSize — предел тела кадра, если другой не задан
+const DefaultMaxFrameSize = 64 << 10
@@ -0,0 +1,105 @@
+package protocol
+
+import (
+	"crypto/sha256"
+	"crypto/sha3"
+	"crypto/sha512"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/blake2b"
+)
+
+// digests — хеш-функции алгоритмов без параметров
+var digests = map[string]func(data []byte) []byte{
+	AlgorithmSha256: func(data []byte) []byte {
+		sum := sha256.Sum256(data)
+		return sum[:]
+	},
+	AlgorithmSha512: func(data []byte) []byte {
+		sum := sha512.Sum512(data)
+		return sum[:]
+	},
+	AlgorithmBlake2b: func(data []byte) []byte {
+		sum := blake2b.Sum256(data)
+		return sum[:]
+	},
+	AlgorithmSha3256: func(data []byte) []byte {
+		sum := sha3.Sum256(data)
+		return sum[:]
+	},
+}
+
+// DigestAlgorithms — алгоритмы на обычных хеш-функциях в порядке предпочтения
+var DigestAlgorithms = []string{AlgorithmSha256, AlgorithmSha512, AlgorithmBlake2b, AlgorithmSha3256}
+
+// Digest возвращает хеш-функцию алгоритма без параметров
+func Digest(algorithm string) (func(data []byte) []byte, bool) {
+	sum, ok := digests[algorithm]
+	return sum, ok
+}
+
+// Hasher возвращает хеш-функцию для поля алгоритма заголовка,
+// включая параметризованные: "argon2id$m=8192,t=1,p=1"
+func Hasher(algorithm string) (func(data []byte) []byte, error) {
+	name, params, hasParams := strings.Cut(algorithm, AlgorithmParamsSeparator)
+	if name == AlgorithmArgon2id {
+		if !hasParams {
+			return nil, fmt.Errorf("missing argon2 params: %s", algorithm)
+		}
+
+		p, err := ParseArgon2Params(params)
+		if err != nil {
+			return nil, err
+		}
+
+		return func(data []byte) []byte {
+			return Argon2Key(data, p)
+		}, nil
+	}
+
+	if sum, ok := digests[name]; ok && !hasParams {
+		return sum, nil
+	}
+
+	return nil, fmt.Errorf("unsupported algorithm: %s", algorithm)
+}
+
+// Argon2Key считает Argon2id с фиксированной солью: уникальность входа обеспечивает nonce
+func Argon2Key(data []byte, p Argon2Params) []byte {
+	return argon2.IDKey(data, Argon2Salt, p.Time, p.Memory, p.Threads, Argon2KeyLen)
+}
+
+// HasLeadingZeroBits проверяет, что дайджест начинается как минимум с bits нулевых бит
+func HasLeadingZeroBits(digest []byte, bits int) bool {
+	if bits < 0 || bits > len(digest)*8 {
+		return false
+	}
+
+	full := bits / 8
+	for _, b := range digest[:full] {
+		if b != 0 {
+			return false
+		}
+	}
+
+	rem := bits % 8
+	if rem == 0 {
+		return true
+	}
+
+	return digest[full]>>(8-rem) == 0
+}
+
+// MeetsDifficulty проверяет дайджест с учётом версии заголовка
+func MeetsDifficulty(digest []byte, version, difficulty int) (bool, error) {
+	switch version {
+	case HeaderVersionHex:
+		return HasLeadingZeroBits(digest, difficulty*4), nil
+	case HeaderVersionBits, HeaderVersionEncoded:
+		return HasLeadingZeroBits(digest, difficulty), nil
+	default:
+		return false, fmt.Errorf("unsupported header version: %d", version)
+	}
+}
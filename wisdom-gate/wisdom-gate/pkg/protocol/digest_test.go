@@ -0,0 +1,27 @@
+package protocol
+
+import "testing"
+
+func TestHasLeadingZeroBits(t *testing.T) {
+	tests := []struct {
+		name   string
+		digest []byte
+		bits   int
+		want   bool
+	}{
+		{name: "zero bits always pass", digest: []byte{0xff}, bits: 0, want: true},
+		{name: "whole zero byte", digest: []byte{0x00, 0xff}, bits: 8, want: true},
+		{name: "partial byte satisfied", digest: []byte{0x00, 0x1f}, bits: 11, want: true},
+		{name: "partial byte not satisfied", digest: []byte{0x00, 0x1f}, bits: 12, want: false},
+		{name: "more bits than digest", digest: []byte{0x00}, bits: 9, want: false},
+		{name: "negative bits", digest: []byte{0x00}, bits: -1, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HasLeadingZeroBits(tt.digest, tt.bits); got != tt.want {
+				t.Errorf("HasLeadingZeroBits() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
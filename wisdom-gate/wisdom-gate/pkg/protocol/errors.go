@@ -0,0 +1,80 @@
+package protocol
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrorCode — стабильный код ошибки в теле ERR, по нему клиент выбирает реакцию
+type ErrorCode string
+
+const (
+	CodePoWExpired  ErrorCode = "POW_EXPIRED"
+	CodePoWReplay   ErrorCode = "POW_REPLAY"
+	CodePoWInvalid  ErrorCode = "POW_INVALID"
+	CodeRateLimited ErrorCode = "RATE_LIMITED"
+	CodeUnavailable ErrorCode = "UNAVAILABLE"
+	// CodeBusy — соединений слишком много, сервер закрывает новое сразу после ERR
+	CodeBusy        ErrorCode = "BUSY"
+	CodeBadFrame    ErrorCode = "BAD_FRAME"
+	CodeBadRequest  ErrorCode = "BAD_REQUEST"
+	CodeUnsupported ErrorCode = "UNSUPPORTED"
+	CodeInternal    ErrorCode = "INTERNAL"
+)
+
+const (
+	fieldCode       = "code"
+	fieldRetryable  = "retryable"
+	fieldRetryAfter = "retry_after"
+	fieldMessage    = "message"
+
+	// legacyErrorPrefix — тело ERR до появления кодов: "ERROR: <текст>"
+	legacyErrorPrefix = "ERROR: "
+)
+
+// ErrorResponse — тело ERR: "code=POW_EXPIRED;retryable=true;retry_after=1000;message=challenge expired".
+// retry_after — подсказка в миллисекундах, сообщение не содержит ';'
+type ErrorResponse struct {
+	Code       ErrorCode
+	Message    string
+	Retryable  bool
+	RetryAfter time.Duration
+}
+
+func (e *ErrorResponse) String() string {
+	kv := []string{
+		fieldCode, string(e.Code),
+		fieldRetryable, strconv.FormatBool(e.Retryable),
+	}
+	if e.RetryAfter > 0 {
+		kv = append(kv, fieldRetryAfter, strconv.FormatInt(e.RetryAfter.Milliseconds(), 10))
+	}
+	kv = append(kv, fieldMessage, strings.ReplaceAll(e.Message, handshakeFieldSeparator, ","))
+
+	return joinFields(kv...)
+}
+
+// ParseErrorResponse разбирает тело ERR. Тело старого формата или без кода
+// возвращается как сообщение с пустым Code
+func ParseErrorResponse(body string) *ErrorResponse {
+	if message, ok := strings.CutPrefix(body, legacyErrorPrefix); ok {
+		return &ErrorResponse{Message: message}
+	}
+
+	fields, err := splitFields(body)
+	if err != nil || fields[fieldCode] == "" {
+		return &ErrorResponse{Message: body}
+	}
+
+	e := &ErrorResponse{
+		Code:    ErrorCode(fields[fieldCode]),
+		Message: fields[fieldMessage],
+	}
+	e.Retryable, _ = strconv.ParseBool(fields[fieldRetryable])
+	if ms, err := strconv.ParseInt(fields[fieldRetryAfter], 10, 64); err == nil && ms > 0 {
+		e.RetryAfter = time.Duration(ms) * time.Millisecond
+	}
+
+	return e
+}
@@ -0,0 +1,71 @@
+package protocol
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestErrorResponse_String(t *testing.T) {
+	tests := []struct {
+		name string
+		resp *ErrorResponse
+		want string
+	}{
+		{
+			name: "with retry hint",
+			resp: &ErrorResponse{Code: CodeRateLimited, Message: "rate limit exceeded", Retryable: true, RetryAfter: 1500 * time.Millisecond},
+			want: "code=RATE_LIMITED;retryable=true;retry_after=1500;message=rate limit exceeded",
+		},
+		{
+			name: "separator in message",
+			resp: &ErrorResponse{Code: CodeBadRequest, Message: "bad; request"},
+			want: "code=BAD_REQUEST;retryable=false;message=bad, request",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.resp.String(); got != tt.want {
+				t.Errorf("ErrorResponse.String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrorResponse(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want *ErrorResponse
+	}{
+		{
+			name: "coded",
+			body: "code=POW_EXPIRED;retryable=true;retry_after=250;message=challenge expired",
+			want: &ErrorResponse{Code: CodePoWExpired, Message: "challenge expired", Retryable: true, RetryAfter: 250 * time.Millisecond},
+		},
+		{
+			name: "unknown fields are ignored",
+			body: "code=UNAVAILABLE;retryable=true;region=eu;message=service temporarily unavailable",
+			want: &ErrorResponse{Code: CodeUnavailable, Message: "service temporarily unavailable", Retryable: true},
+		},
+		{
+			name: "legacy body",
+			body: "ERROR: rate limit exceeded",
+			want: &ErrorResponse{Message: "rate limit exceeded"},
+		},
+		{
+			name: "free text",
+			body: "something went wrong",
+			want: &ErrorResponse{Message: "something went wrong"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseErrorResponse(tt.body); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseErrorResponse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
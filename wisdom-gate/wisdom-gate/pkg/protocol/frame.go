@@ -0,0 +1,156 @@
+package protocol
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+)
+
+var (
+	ErrFrameTooLarge           = errors.New("frame too large")
+	ErrBadFrameMagic           = errors.New("bad frame magic")
+	ErrUnsupportedFrameVersion = errors.New("unsupported frame version")
+	ErrInvalidCommand          = errors.New("invalid command")
+)
+
+// frameHeaderSize — magic, версия и длина команды
+const frameHeaderSize = 3
+
+// BuffersWriter реализуют обёртки соединений, которые сами передают буферы
+// нижележащему соединению: net.Buffers.WriteTo пишет одним системным вызовом
+// только в соединения пакета net, а в обёртку — каждым буфером отдельно
+type BuffersWriter interface {
+	WriteBuffers(bufs *net.Buffers) (int64, error)
+}
+
+// WriteFrame пишет кадр v2 одним системным вызовом, не копируя тело
+func WriteFrame(w io.Writer, command string, payload []byte) error {
+	if len(command) == 0 || len(command) > 255 {
+		return fmt.Errorf("%w: %q", ErrInvalidCommand, command)
+	}
+	if uint64(len(payload)) > uint64(^uint32(0)) {
+		return ErrFrameTooLarge
+	}
+
+	header := make([]byte, 0, frameHeaderSize+len(command)+4)
+	header = append(header, FrameMagic, FramingV2, byte(len(command)))
+	header = append(header, command...)
+	header = binary.BigEndian.AppendUint32(header, uint32(len(payload)))
+
+	bufs := net.Buffers{header}
+	if len(payload) > 0 {
+		// Пустая запись на синхронных соединениях вроде net.Pipe ждёт читателя
+		bufs = append(bufs, payload)
+	}
+	if bw, ok := w.(BuffersWriter); ok {
+		_, err := bw.WriteBuffers(&bufs)
+		return err
+	}
+	_, err := bufs.WriteTo(w)
+	return err
+}
+
+// FrameReader читает кадры v2. Тело, возвращаемое ReadFrame, ссылается на
+// внутренний буфер и действительно только до следующего вызова
+type FrameReader struct {
+	r            *bufio.Reader
+	maxFrameSize int
+	buf          []byte
+}
+
+func NewFrameReader(r *bufio.Reader, maxFrameSize int) *FrameReader {
+	if maxFrameSize <= 0 {
+		maxFrameSize = DefaultMaxFrameSize
+	}
+	return &FrameReader{r: r, maxFrameSize: maxFrameSize}
+}
+
+func (fr *FrameReader) ReadFrame() (string, []byte, error) {
+	header, err := fr.r.Peek(frameHeaderSize)
+	if err != nil {
+		return "", nil, err
+	}
+	if header[0] != FrameMagic {
+		return "", nil, ErrBadFrameMagic
+	}
+	if header[1] != FramingV2 {
+		return "", nil, fmt.Errorf("%w: %d", ErrUnsupportedFrameVersion, header[1])
+	}
+	commandLen := int(header[2])
+	if commandLen == 0 {
+		return "", nil, ErrInvalidCommand
+	}
+	if _, err := fr.r.Discard(frameHeaderSize); err != nil {
+		return "", nil, err
+	}
+
+	command := make([]byte, commandLen)
+	if _, err := io.ReadFull(fr.r, command); err != nil {
+		return "", nil, unexpectedEOF(err)
+	}
+
+	var size [4]byte
+	if _, err := io.ReadFull(fr.r, size[:]); err != nil {
+		return "", nil, unexpectedEOF(err)
+	}
+	length := binary.BigEndian.Uint32(size[:])
+	if uint64(length) > uint64(fr.maxFrameSize) {
+		return "", nil, fmt.Errorf("%w: %d > %d", ErrFrameTooLarge, length, fr.maxFrameSize)
+	}
+
+	payload, err := fr.readPayload(int(length))
+	if err != nil {
+		return "", nil, unexpectedEOF(err)
+	}
+
+	return string(command), payload, nil
+}
+
+// ReadMessage читает кадр и копирует тело в Message
+func (fr *FrameReader) ReadMessage() (*Message, error) {
+	token, payload, err := fr.ReadFrame()
+	if err != nil {
+		return nil, err
+	}
+
+	command, id, err := parseToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Message{Command: command, Body: string(payload), ID: id}, nil
+}
+
+// readPayload отдаёт тело прямо из буфера bufio, если оно там помещается,
+// иначе читает в переиспользуемый буфер
+func (fr *FrameReader) readPayload(n int) ([]byte, error) {
+	if n <= fr.r.Size() {
+		payload, err := fr.r.Peek(n)
+		if err != nil {
+			return nil, err
+		}
+		_, _ = fr.r.Discard(n)
+		return payload, nil
+	}
+
+	if cap(fr.buf) < n {
+		fr.buf = make([]byte, n)
+	}
+	payload := fr.buf[:n]
+	if _, err := io.ReadFull(fr.r, payload); err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// unexpectedEOF: обрыв посреди кадра — не штатное закрытие соединения
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,233 @@
+package protocol
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestFrame_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		payload string
+	}{
+		{name: "empty body", command: CmdDISC},
+		{name: "body with newline", command: CmdQOT, payload: "first line\nsecond line"},
+		{name: "body larger than reader buffer", command: CmdRES, payload: strings.Repeat("x", 10000)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteFrame(&buf, tt.command, []byte(tt.payload)); err != nil {
+				t.Fatalf("WriteFrame() error = %v", err)
+			}
+
+			fr := NewFrameReader(bufio.NewReader(&buf), 16<<10)
+			command, payload, err := fr.ReadFrame()
+			if err != nil {
+				t.Fatalf("ReadFrame() error = %v", err)
+			}
+			if command != tt.command || string(payload) != tt.payload {
+				t.Errorf("ReadFrame() = %q, %d bytes, want %q, %d bytes", command, len(payload), tt.command, len(tt.payload))
+			}
+		})
+	}
+}
+
+func TestFrameReader_Errors(t *testing.T) {
+	frame := func(command string, payload string) []byte {
+		var buf bytes.Buffer
+		_ = WriteFrame(&buf, command, []byte(payload))
+		return buf.Bytes()
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{
+			name:    "frame too large",
+			data:    frame(CmdRES, strings.Repeat("x", 65)),
+			wantErr: ErrFrameTooLarge,
+		},
+		{
+			name:    "bad magic",
+			data:    []byte("REQ\n"),
+			wantErr: ErrBadFrameMagic,
+		},
+		{
+			name:    "unsupported version",
+			data:    []byte{FrameMagic, 3, 3, 'R', 'E', 'Q', 0, 0, 0, 0},
+			wantErr: ErrUnsupportedFrameVersion,
+		},
+		{
+			name:    "empty command",
+			data:    []byte{FrameMagic, FramingV2, 0, 0, 0, 0, 0},
+			wantErr: ErrInvalidCommand,
+		},
+		{
+			name:    "truncated payload",
+			data:    frame(CmdRES, "solution")[:12],
+			wantErr: io.ErrUnexpectedEOF,
+		},
+		{
+			name:    "clean eof",
+			data:    nil,
+			wantErr: io.EOF,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fr := NewFrameReader(bufio.NewReader(bytes.NewReader(tt.data)), 64)
+			if _, _, err := fr.ReadFrame(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("ReadFrame() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWriteFrame_InvalidCommand(t *testing.T) {
+	for _, command := range []string{"", strings.Repeat("C", 256)} {
+		if err := WriteFrame(io.Discard, command, nil); !errors.Is(err, ErrInvalidCommand) {
+			t.Errorf("WriteFrame(%d byte command) error = %v, want %v", len(command), err, ErrInvalidCommand)
+		}
+	}
+}
+
+// buffersRecorder — обёртка соединения, принимающая буферы целиком
+type buffersRecorder struct {
+	bytes.Buffer
+	calls int
+}
+
+func (r *buffersRecorder) WriteBuffers(bufs *net.Buffers) (int64, error) {
+	r.calls++
+	return bufs.WriteTo(&r.Buffer)
+}
+
+func TestWriteFrame_BuffersWriter(t *testing.T) {
+	var w buffersRecorder
+	if err := WriteFrame(&w, CmdQOT, []byte("quote")); err != nil {
+		t.Fatalf("WriteFrame() error = %v", err)
+	}
+	if w.calls != 1 {
+		t.Errorf("WriteBuffers() calls = %d, want 1", w.calls)
+	}
+
+	command, payload, err := NewFrameReader(bufio.NewReader(&w.Buffer), 0).ReadFrame()
+	if err != nil || command != CmdQOT || string(payload) != "quote" {
+		t.Errorf("ReadFrame() = %q, %q, %v, want QOT quote", command, payload, err)
+	}
+}
+
+func TestConn_DetectsFraming(t *testing.T) {
+	tests := []struct {
+		name        string
+		framing     int
+		wantFraming int
+	}{
+		{name: "text v1", framing: FramingV1, wantFraming: FramingV1},
+		{name: "binary v2", framing: FramingV2, wantFraming: FramingV2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientSide, serverSide := net.Pipe()
+			defer clientSide.Close()
+			defer serverSide.Close()
+
+			client := NewClientConn(clientSide, tt.framing, 0)
+			server := NewServerConn(serverSide, 0)
+
+			go func() {
+				_ = client.WriteMessage(&Message{Command: CmdREQ, Body: AlgorithmSha256})
+			}()
+
+			msg, err := server.ReadMessage()
+			if err != nil {
+				t.Fatalf("ReadMessage() error = %v", err)
+			}
+			if msg.Command != CmdREQ || msg.Body != AlgorithmSha256 {
+				t.Errorf("ReadMessage() = %+v, want REQ %s", msg, AlgorithmSha256)
+			}
+			if server.Framing() != tt.wantFraming {
+				t.Errorf("Framing() = %d, want %d", server.Framing(), tt.wantFraming)
+			}
+
+			// Ответ уходит в формате клиента, в том числе через WriteMessage
+			go func() {
+				_ = WriteMessage(server, &Message{Command: CmdQOT, Body: "quote"})
+			}()
+
+			reply, err := client.ReadMessage()
+			if err != nil {
+				t.Fatalf("client ReadMessage() error = %v", err)
+			}
+			if reply.Command != CmdQOT || reply.Body != "quote" {
+				t.Errorf("client ReadMessage() = %+v, want QOT quote", reply)
+			}
+		})
+	}
+}
+
+func TestConn_LineTooLong(t *testing.T) {
+	clientSide, serverSide := net.Pipe()
+	defer clientSide.Close()
+	defer serverSide.Close()
+
+	go func() {
+		_, _ = clientSide.Write([]byte("RES 10000 |" + strings.Repeat("x", 10000) + "\n"))
+	}()
+
+	server := NewServerConn(serverSide, 1024)
+	if _, err := server.ReadMessage(); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("ReadMessage() error = %v, want %v", err, ErrFrameTooLarge)
+	}
+}
+
+func TestConn_RequestID(t *testing.T) {
+	for _, framing := range []int{FramingV1, FramingV2} {
+		clientSide, serverSide := net.Pipe()
+
+		client := NewClientConn(clientSide, framing, 0)
+		server := NewServerConn(serverSide, 0)
+
+		want := &Message{Command: CmdREQ, Body: AlgorithmSha256, ID: "req-42"}
+		go func() {
+			_ = client.WriteMessage(want)
+		}()
+
+		got, err := server.ReadMessage()
+		if err != nil {
+			t.Fatalf("framing %d: ReadMessage() error = %v", framing, err)
+		}
+		if *got != *want {
+			t.Errorf("framing %d: ReadMessage() = %+v, want %+v", framing, got, want)
+		}
+
+		_ = clientSide.Close()
+		_ = serverSide.Close()
+	}
+}
+
+func TestReadMessage_InvalidRequestID(t *testing.T) {
+	tests := []string{
+		"REQ#\n",
+		"REQ#id;1 6 |sha256\n",
+		"REQ#" + strings.Repeat("x", MaxRequestIDLength+1) + "\n",
+	}
+
+	for _, line := range tests {
+		if _, err := ReadMessage(bufio.NewReader(strings.NewReader(line))); !errors.Is(err, ErrInvalidCommand) {
+			t.Errorf("ReadMessage(%q) error = %v, want %v", line, err, ErrInvalidCommand)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package protocol
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type Message struct {
+	Command string
+	Body    string
+	// ID — необязательный идентификатор запроса, сервер повторяет его в каждом
+	// ответе. На проводе идёт после команды: "REQ#42"
+	ID string
+}
+
+func (m *Message) String() string {
+	if m.Body == "" {
+		return m.token()
+	}
+	return fmt.Sprintf("%s %d |%s", m.token(), len(m.Body), m.Body)
+}
+
+func (m *Message) token() string {
+	if m.ID == "" {
+		return m.Command
+	}
+	return m.Command + RequestIDSeparator + m.ID
+}
+
+// parseToken отделяет идентификатор запроса от команды
+func parseToken(token string) (string, string, error) {
+	command, id, ok := strings.Cut(token, RequestIDSeparator)
+	if !ok {
+		return token, "", nil
+	}
+
+	if id == "" || len(id) > MaxRequestIDLength || strings.ContainsFunc(id, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_')
+	}) {
+		return "", "", fmt.Errorf("%w: bad request id %q", ErrInvalidCommand, id)
+	}
+
+	return command, id, nil
+}
+
+// MessageWriter пишет сообщение в формате своего соединения, см. Conn
+type MessageWriter interface {
+	WriteMessage(msg *Message) error
+}
+
+// WriteMessage пишет сообщение текстовой строкой v1. Если w сам знает формат
+// соединения (MessageWriter), запись делегируется ему
+func WriteMessage(w io.Writer, msg *Message) error {
+	if mw, ok := w.(MessageWriter); ok {
+		return mw.WriteMessage(msg)
+	}
+
+	_, err := fmt.Fprintf(w, "%s\n", msg.String())
+	return err
+}
+
+// ReadMessage читает сообщение v1, строка ограничена DefaultMaxFrameSize
+func ReadMessage(r *bufio.Reader) (*Message, error) {
+	return readLineMessage(r, DefaultMaxFrameSize+lineOverhead)
+}
+
+// readLineMessage читает сообщение v1, maxLineSize ограничивает длину строки
+func readLineMessage(r *bufio.Reader, maxLineSize int) (*Message, error) {
+	line, err := readLine(r, maxLineSize)
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSpace(line)
+
+	parts := strings.SplitN(line, " ", 2)
+	command, id, err := parseToken(parts[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if len(parts) == 1 {
+		// Command only
+		return &Message{Command: command, ID: id}, nil
+	}
+
+	bodyPart := parts[1]
+	bodyParts := strings.SplitN(bodyPart, " |", 2)
+	if len(bodyParts) != 2 {
+		return nil, fmt.Errorf("invalid message format")
+	}
+
+	length, err := strconv.Atoi(bodyParts[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid body length: %w", err)
+	}
+
+	body := bodyParts[1]
+	if len(body) != length {
+		return nil, fmt.Errorf("body length mismatch: expected %d, got %d", length, len(body))
+	}
+
+	return &Message{
+		Command: command,
+		Body:    body,
+		ID:      id,
+	}, nil
+}
+
+func readLine(r *bufio.Reader, maxLineSize int) (string, error) {
+	var line []byte
+	for {
+		chunk, err := r.ReadSlice('\n')
+		if len(line)+len(chunk) > maxLineSize {
+			return "", fmt.Errorf("%w: line exceeds %d bytes", ErrFrameTooLarge, maxLineSize)
+		}
+		line = append(line, chunk...)
+
+		if !errors.Is(err, bufio.ErrBufferFull) {
+			return string(line), err
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package protocol
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Поля тел HELLO и VER: "key=value;key=value". Неизвестные ключи
+// пропускаются, чтобы новые поля не ломали старые реализации
+const (
+	handshakeFieldSeparator = ";"
+
+	fieldVersions     = "versions"
+	fieldVersion      = "version"
+	fieldAlgorithms   = "algorithms"
+	fieldDifficulty   = "difficulty"
+	fieldMaxFrameSize = "max_frame"
+)
+
+// Hello — предложение клиента: версии протокола и алгоритмы PoW, которые он поддерживает
+type Hello struct {
+	Versions   []int
+	Algorithms []string
+}
+
+func (h *Hello) String() string {
+	versions := make([]string, len(h.Versions))
+	for i, v := range h.Versions {
+		versions[i] = strconv.Itoa(v)
+	}
+
+	return joinFields(
+		fieldVersions, strings.Join(versions, ","),
+		fieldAlgorithms, strings.Join(h.Algorithms, AlgorithmSeparator),
+	)
+}
+
+func ParseHello(body string) (*Hello, error) {
+	fields, err := splitFields(body)
+	if err != nil {
+		return nil, err
+	}
+
+	h := &Hello{}
+	if v := fields[fieldVersions]; v != "" {
+		for _, s := range strings.Split(v, ",") {
+			version, err := strconv.Atoi(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid version %q: %w", s, err)
+			}
+			h.Versions = append(h.Versions, version)
+		}
+	}
+	if len(h.Versions) == 0 {
+		return nil, fmt.Errorf("hello without versions")
+	}
+
+	if a := fields[fieldAlgorithms]; a != "" {
+		h.Algorithms = strings.Split(a, AlgorithmSeparator)
+	}
+
+	return h, nil
+}
+
+// VersionInfo — ответ сервера на HELLO: выбранная версия и текущие ограничения
+type VersionInfo struct {
+	Version      int
+	Algorithms   []string
+	Difficulty   int
+	MaxFrameSize int
+}
+
+func (v *VersionInfo) String() string {
+	return joinFields(
+		fieldVersion, strconv.Itoa(v.Version),
+		fieldAlgorithms, strings.Join(v.Algorithms, AlgorithmSeparator),
+		fieldDifficulty, strconv.Itoa(v.Difficulty),
+		fieldMaxFrameSize, strconv.Itoa(v.MaxFrameSize),
+	)
+}
+
+func ParseVersionInfo(body string) (*VersionInfo, error) {
+	fields, err := splitFields(body)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &VersionInfo{}
+	if v.Version, err = strconv.Atoi(fields[fieldVersion]); err != nil {
+		return nil, fmt.Errorf("invalid version: %w", err)
+	}
+	if a := fields[fieldAlgorithms]; a != "" {
+		v.Algorithms = strings.Split(a, AlgorithmSeparator)
+	}
+	if d := fields[fieldDifficulty]; d != "" {
+		if v.Difficulty, err = strconv.Atoi(d); err != nil {
+			return nil, fmt.Errorf("invalid difficulty: %w", err)
+		}
+	}
+	if m := fields[fieldMaxFrameSize]; m != "" {
+		if v.MaxFrameSize, err = strconv.Atoi(m); err != nil {
+			return nil, fmt.Errorf("invalid max frame size: %w", err)
+		}
+	}
+
+	return v, nil
+}
+
+func joinFields(kv ...string) string {
+	parts := make([]string, 0, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		parts = append(parts, kv[i]+"="+kv[i+1])
+	}
+	return strings.Join(parts, handshakeFieldSeparator)
+}
+
+func splitFields(body string) (map[string]string, error) {
+	fields := make(map[string]string)
+	if body == "" {
+		return fields, nil
+	}
+
+	for _, part := range strings.Split(body, handshakeFieldSeparator) {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid handshake field %q", part)
+		}
+		fields[key] = value
+	}
+
+	return fields, nil
+}
@@ -0,0 +1,96 @@
+package protocol
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHello_RoundTrip(t *testing.T) {
+	hello := &Hello{Versions: []int{ProtocolV1, ProtocolV2}, Algorithms: []string{AlgorithmSha256, AlgorithmArgon2id}}
+
+	body := hello.String()
+	if want := "versions=1,2;algorithms=sha-256,argon2id"; body != want {
+		t.Errorf("Hello.String() = %q, want %q", body, want)
+	}
+
+	got, err := ParseHello(body)
+	if err != nil {
+		t.Fatalf("ParseHello() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, hello) {
+		t.Errorf("ParseHello() = %+v, want %+v", got, hello)
+	}
+}
+
+func TestParseHello(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    *Hello
+		wantErr bool
+	}{
+		{
+			name: "versions only",
+			body: "versions=2",
+			want: &Hello{Versions: []int{2}},
+		},
+		{
+			name: "unknown fields are ignored",
+			body: "versions=1;compression=zstd",
+			want: &Hello{Versions: []int{1}},
+		},
+		{
+			name:    "no versions",
+			body:    "algorithms=sha-256",
+			wantErr: true,
+		},
+		{
+			name:    "invalid version",
+			body:    "versions=1,x",
+			wantErr: true,
+		},
+		{
+			name:    "malformed field",
+			body:    "versions",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseHello(tt.body)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseHello() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseHello() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVersionInfo_RoundTrip(t *testing.T) {
+	info := &VersionInfo{
+		Version:      ProtocolV2,
+		Algorithms:   []string{AlgorithmSha256, AlgorithmBlake2b},
+		Difficulty:   20,
+		MaxFrameSize: DefaultMaxFrameSize,
+	}
+
+	body := info.String()
+	if want := "version=2;algorithms=sha-256,blake2b;difficulty=20;max_frame=65536"; body != want {
+		t.Errorf("VersionInfo.String() = %q, want %q", body, want)
+	}
+
+	got, err := ParseVersionInfo(body)
+	if err != nil {
+		t.Fatalf("ParseVersionInfo() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, info) {
+		t.Errorf("ParseVersionInfo() = %+v, want %+v", got, info)
+	}
+
+	if _, err := ParseVersionInfo("algorithms=sha-256"); err == nil {
+		t.Error("ParseVersionInfo() without version error = nil, want error")
+	}
+}
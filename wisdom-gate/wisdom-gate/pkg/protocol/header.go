@@ -0,0 +1,187 @@
+package protocol
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrVersionMismatch    = errors.New("version mismatch")
+	ErrDifficultyMismatch = errors.New("difficulty mismatch")
+	ErrExpiresAtMismatch  = errors.New("expiresAt mismatch")
+	ErrSubjectMismatch    = errors.New("subject mismatch")
+	ErrAlgorithmMismatch  = errors.New("algorithm mismatch")
+	ErrNonceMismatch      = errors.New("nonce mismatch")
+)
+
+type HashcashHeader struct {
+	Version    int
+	Difficulty int
+	ExpiresAt  int64
+	Subject    string
+	Algorithm  string
+	Nonce      string
+	Counter    int64
+}
+
+// fieldEncoding кодирует subject и nonce начиная с HeaderVersionEncoded
+var fieldEncoding = base64.RawURLEncoding
+
+func (h *HashcashHeader) String() string {
+	subject, nonce := h.Subject, h.Nonce
+	if h.Version >= HeaderVersionEncoded {
+		subject = fieldEncoding.EncodeToString([]byte(subject))
+		nonce = fieldEncoding.EncodeToString([]byte(nonce))
+	}
+
+	if h.Counter == 0 {
+		return fmt.Sprintf("%d:%d:%d:%s:%s:%s",
+			h.Version, h.Difficulty, h.ExpiresAt, subject, h.Algorithm, nonce)
+	}
+	return fmt.Sprintf("%d:%d:%d:%s:%s:%s:%s",
+		h.Version, h.Difficulty, h.ExpiresAt, subject, h.Algorithm, nonce,
+		base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%d", h.Counter))))
+}
+
+func ParseHashcashHeader(header string) (*HashcashHeader, error) {
+	parts := strings.Split(header, ":")
+	if len(parts) < 6 {
+		return nil, fmt.Errorf("invalid header format")
+	}
+
+	version, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid version: %w", err)
+	}
+
+	difficulty, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid difficulty: %w", err)
+	}
+
+	expiresAt, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expiresAt: %w", err)
+	}
+
+	h := &HashcashHeader{
+		Version:    version,
+		Difficulty: difficulty,
+		ExpiresAt:  expiresAt,
+	}
+
+	var algIdx int
+	if version >= HeaderVersionEncoded {
+		// Поля не содержат двоеточий, поэтому их число строго фиксировано
+		if len(parts) > 7 {
+			return nil, fmt.Errorf("invalid header format: unexpected trailing fields")
+		}
+
+		algIdx = 4
+		if err := h.decodeFields(parts[3], parts[5]); err != nil {
+			return nil, err
+		}
+	} else {
+		algIdx = algorithmIndex(parts)
+		if len(parts) > algIdx+3 {
+			return nil, fmt.Errorf("invalid header format: unexpected trailing fields")
+		}
+
+		h.Subject = strings.Join(parts[3:algIdx], ":")
+		h.Nonce = parts[algIdx+1]
+	}
+	h.Algorithm = parts[algIdx]
+
+	// Counter есть только в solution и идёт сразу после nonce
+	if len(parts) > algIdx+2 {
+		counterBytes, err := base64.StdEncoding.DecodeString(parts[algIdx+2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid counter: %w", err)
+		}
+		counter, err := strconv.ParseInt(string(counterBytes), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid counter value: %w", err)
+		}
+		h.Counter = counter
+	}
+
+	return h, nil
+}
+
+func (h *HashcashHeader) decodeFields(subject, nonce string) error {
+	decodedSubject, err := fieldEncoding.DecodeString(subject)
+	if err != nil {
+		return fmt.Errorf("invalid subject: %w", err)
+	}
+
+	decodedNonce, err := fieldEncoding.DecodeString(nonce)
+	if err != nil {
+		return fmt.Errorf("invalid nonce: %w", err)
+	}
+
+	h.Subject = string(decodedSubject)
+	h.Nonce = string(decodedNonce)
+
+	return nil
+}
+
+// algorithmIndex находит поле алгоритма в заголовках версий 1 и 2. Subject может содержать двоеточия
+// (IPv6, IP:PORT), поэтому за ним идут алгоритм, nonce и, в решении, counter,
+// а известное имя алгоритма ищется с конца. Для неизвестных алгоритмов
+// сохраняется прежний разбор: subject без двоеточий или вида IP:PORT
+func algorithmIndex(parts []string) int {
+	n := len(parts)
+	switch {
+	case n >= 7 && isKnownAlgorithm(parts[n-3]):
+		return n - 3
+	case isKnownAlgorithm(parts[n-2]):
+		return n - 2
+	case n > 6:
+		return 5
+	default:
+		return 4
+	}
+}
+
+func isKnownAlgorithm(algorithm string) bool {
+	name, _, _ := strings.Cut(algorithm, AlgorithmParamsSeparator)
+	switch name {
+	case AlgorithmSha256, AlgorithmSha512, AlgorithmBlake2b,
+		AlgorithmSha3256, AlgorithmArgon2id:
+		return true
+	}
+
+	return false
+}
+
+func (h *HashcashHeader) IsExpired() bool {
+	return time.Now().Unix() > h.ExpiresAt
+}
+
+func (h *HashcashHeader) ValidateSubject(expected string) bool {
+	return h.Subject == expected
+}
+
+// MatchChallenge сверяет решение с выданным challenge по всем полям, кроме Counter.
+func (h *HashcashHeader) MatchChallenge(challenge *HashcashHeader) error {
+	switch {
+	case h.Version != challenge.Version:
+		return ErrVersionMismatch
+	case h.Difficulty != challenge.Difficulty:
+		return ErrDifficultyMismatch
+	case h.ExpiresAt != challenge.ExpiresAt:
+		return ErrExpiresAtMismatch
+	case h.Subject != challenge.Subject:
+		return ErrSubjectMismatch
+	case h.Algorithm != challenge.Algorithm:
+		return ErrAlgorithmMismatch
+	case h.Nonce != challenge.Nonce:
+		return ErrNonceMismatch
+	}
+
+	return nil
+}
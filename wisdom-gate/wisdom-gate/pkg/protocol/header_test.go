@@ -1,6 +1,7 @@
-package usecase
+package protocol
 
 import (
+	"errors"
 	"testing"
 	"time"
 )
@@ -83,6 +84,79 @@ func TestParseHashcashHeader(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name:   "ip subject with counter",
+			header: "2:20:1234567890:192.0.2.10:sha-256:test-nonce:MTIzNDU=",
+			want: &HashcashHeader{
+				Version:    2,
+				Difficulty: 20,
+				ExpiresAt:  1234567890,
+				Subject:    "192.0.2.10",
+				Algorithm:  "sha-256",
+				Nonce:      "test-nonce",
+				Counter:    12345,
+			},
+			wantErr: false,
+		},
+		{
+			name:   "ipv6 prefix subject",
+			header: "2:20:1234567890:2001:db8:1:2::/64:argon2id$m=8192,t=1,p=1:test-nonce",
+			want: &HashcashHeader{
+				Version:    2,
+				Difficulty: 20,
+				ExpiresAt:  1234567890,
+				Subject:    "2001:db8:1:2::/64",
+				Algorithm:  "argon2id$m=8192,t=1,p=1",
+				Nonce:      "test-nonce",
+			},
+			wantErr: false,
+		},
+		{
+			name:   "ipv6 subject with counter",
+			header: "2:20:1234567890:2001:db8::1:sha-512:test-nonce:MTIzNDU=",
+			want: &HashcashHeader{
+				Version:    2,
+				Difficulty: 20,
+				ExpiresAt:  1234567890,
+				Subject:    "2001:db8::1",
+				Algorithm:  "sha-512",
+				Nonce:      "test-nonce",
+				Counter:    12345,
+			},
+			wantErr: false,
+		},
+		{
+			name:   "encoded header",
+			header: "3:20:1234567890:Wzo6MV06NTAwMA:sha-256:bm9uY2UudGFn:MTIzNDU=",
+			want: &HashcashHeader{
+				Version:    3,
+				Difficulty: 20,
+				ExpiresAt:  1234567890,
+				Subject:    "[::1]:5000",
+				Algorithm:  "sha-256",
+				Nonce:      "nonce.tag",
+				Counter:    12345,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "encoded header with trailing field",
+			header:  "3:20:1234567890:Wzo6MV06NTAwMA:sha-256:bm9uY2UudGFn:MTIzNDU=:extra",
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "encoded header with invalid subject",
+			header:  "3:20:1234567890:not base64:sha-256:bm9uY2UudGFn",
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "legacy header with trailing field",
+			header:  "2:20:1234567890:192.0.2.10:sha-256:test-nonce:MTIzNDU=:extra",
+			want:    nil,
+			wantErr: true,
+		},
 		{
 			name:    "invalid header format",
 			header:  "invalid",
@@ -139,6 +213,32 @@ func TestParseHashcashHeader(t *testing.T) {
 	}
 }
 
+func TestHashcashHeader_EncodedRoundTrip(t *testing.T) {
+	subjects := []string{"192.0.2.10", "[::1]:5000", "2001:db8:1:2::/64", "a:b:c", ""}
+
+	for _, subject := range subjects {
+		for _, counter := range []int64{0, 42} {
+			h := &HashcashHeader{
+				Version:    HeaderVersionEncoded,
+				Difficulty: 20,
+				ExpiresAt:  1234567890,
+				Subject:    subject,
+				Algorithm:  "argon2id$m=8192,t=1,p=1",
+				Nonce:      "nonce:with.separators",
+				Counter:    counter,
+			}
+
+			got, err := ParseHashcashHeader(h.String())
+			if err != nil {
+				t.Fatalf("ParseHashcashHeader(%q) error = %v", h.String(), err)
+			}
+			if *got != *h {
+				t.Errorf("ParseHashcashHeader(%q) = %+v, want %+v", h.String(), *got, *h)
+			}
+		}
+	}
+}
+
 func TestHashcashHeader_IsExpired(t *testing.T) {
 	now := time.Now().Unix()
 
@@ -220,3 +320,67 @@ func TestHashcashHeader_ValidateSubject(t *testing.T) {
 		})
 	}
 }
+
+func TestHashcashHeader_MatchChallenge(t *testing.T) {
+	challenge := &HashcashHeader{
+		Version:    1,
+		Difficulty: 4,
+		ExpiresAt:  1234567890,
+		Subject:    "127.0.0.1:8080",
+		Algorithm:  "sha-256",
+		Nonce:      "test-nonce",
+	}
+
+	tests := []struct {
+		name   string
+		tamper func(h *HashcashHeader)
+		want   error
+	}{
+		{
+			name:   "only counter differs",
+			tamper: func(h *HashcashHeader) { h.Counter = 42 },
+			want:   nil,
+		},
+		{
+			name:   "version differs",
+			tamper: func(h *HashcashHeader) { h.Version = 2 },
+			want:   ErrVersionMismatch,
+		},
+		{
+			name:   "difficulty differs",
+			tamper: func(h *HashcashHeader) { h.Difficulty = 1 },
+			want:   ErrDifficultyMismatch,
+		},
+		{
+			name:   "expiresAt differs",
+			tamper: func(h *HashcashHeader) { h.ExpiresAt++ },
+			want:   ErrExpiresAtMismatch,
+		},
+		{
+			name:   "subject differs",
+			tamper: func(h *HashcashHeader) { h.Subject = "10.0.0.1:8080" },
+			want:   ErrSubjectMismatch,
+		},
+		{
+			name:   "algorithm differs",
+			tamper: func(h *HashcashHeader) { h.Algorithm = "sha-512" },
+			want:   ErrAlgorithmMismatch,
+		},
+		{
+			name:   "nonce differs",
+			tamper: func(h *HashcashHeader) { h.Nonce = "other-nonce" },
+			want:   ErrNonceMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			solution := *challenge
+			tt.tamper(&solution)
+
+			if got := solution.MatchChallenge(challenge); !errors.Is(got, tt.want) {
+				t.Errorf("HashcashHeader.MatchChallenge() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package protocol
+
+import (
+	"context"
+	"crypto/sha1" //nolint:gosec // SHA-1 задан спецификацией Hashcash v1
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// stampCheckInterval — через сколько попыток решатель проверяет отмену контекста
+const stampCheckInterval = 1 << 14
+
+// SupportedAlgorithms возвращает алгоритмы, которые умеет решать Solve, для тела REQ
+func SupportedAlgorithms() string {
+	names := append(append([]string(nil), DigestAlgorithms...), AlgorithmArgon2id)
+	return strings.Join(names, AlgorithmSeparator)
+}
+
+// Solve подбирает counter для challenge в workers горутинах и возвращает решение
+func Solve(ctx context.Context, challenge *HashcashHeader, workers int) (*HashcashHeader, error) {
+	sum, err := Hasher(challenge.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	solutions := make(chan *HashcashHeader, 1)
+	errs := make(chan error, workers)
+
+	var wg sync.WaitGroup
+	for worker := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			candidate := *challenge
+			for counter := int64(worker + 1); ; counter += int64(workers) {
+				if ctx.Err() != nil {
+					return
+				}
+
+				candidate.Counter = counter
+				ok, err := MeetsDifficulty(sum([]byte(candidate.String())), candidate.Version, candidate.Difficulty)
+				if err != nil {
+					errs <- err
+					return
+				}
+
+				if ok {
+					select {
+					case solutions <- &candidate:
+					default:
+					}
+					cancel()
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	select {
+	case solution := <-solutions:
+		return solution, nil
+	case err := <-errs:
+		return nil, err
+	default:
+		return nil, ctx.Err()
+	}
+}
+
+// IsStampTemplate распознаёт шаблон марки Hashcash v1 с пустым counter
+func IsStampTemplate(challenge string) bool {
+	stamp, err := ParseHashcashStamp(challenge)
+	return err == nil && stamp.Counter == ""
+}
+
+// SolveStamp подбирает counter марки Hashcash v1 так же, как утилита hashcash:
+// SHA-1 всей марки должен начинаться с Bits нулевых бит
+func SolveStamp(ctx context.Context, template *HashcashStamp) (*HashcashStamp, error) {
+	if template.Bits > sha1.Size*8 {
+		return nil, errors.New("stamp bits exceed digest size")
+	}
+
+	stamp := *template
+	for counter := int64(0); ; counter++ {
+		if counter%stampCheckInterval == 0 && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		stamp.Counter = strconv.FormatInt(counter, 36)
+		digest := sha1.Sum([]byte(stamp.String())) //nolint:gosec // SHA-1 задан спецификацией Hashcash v1
+		if HasLeadingZeroBits(digest[:], stamp.Bits) {
+			return &stamp, nil
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package protocol
+
+import (
+	"context"
+	"crypto/sha1" //nolint:gosec // SHA-1 задан спецификацией Hashcash v1
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSolve(t *testing.T) {
+	algorithms := append(append([]string(nil), DigestAlgorithms...), "argon2id$m=64,t=1,p=1")
+
+	for _, algorithm := range algorithms {
+		t.Run(algorithm, func(t *testing.T) {
+			challenge := &HashcashHeader{
+				Version:    HeaderVersionEncoded,
+				Difficulty: 6,
+				ExpiresAt:  time.Now().Add(time.Minute).Unix(),
+				Subject:    "[::1]:5000",
+				Algorithm:  algorithm,
+				Nonce:      "nonce",
+			}
+
+			solution, err := Solve(context.Background(), challenge, 4)
+			if err != nil {
+				t.Fatalf("Solve() error = %v", err)
+			}
+
+			sum, err := Hasher(algorithm)
+			if err != nil {
+				t.Fatalf("Hasher() error = %v", err)
+			}
+			ok, err := MeetsDifficulty(sum([]byte(solution.String())), solution.Version, solution.Difficulty)
+			if err != nil || !ok {
+				t.Errorf("Solve() = %q does not meet difficulty", solution.String())
+			}
+
+			solution.Counter = 0
+			if *solution != *challenge {
+				t.Errorf("Solve() changed challenge fields: %+v, want %+v", *solution, *challenge)
+			}
+		})
+	}
+}
+
+func TestSolve_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	challenge := &HashcashHeader{Version: HeaderVersionBits, Difficulty: 256, Algorithm: AlgorithmSha256, Nonce: "nonce"}
+	if _, err := Solve(ctx, challenge, 2); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Solve() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestSolve_UnsupportedAlgorithm(t *testing.T) {
+	challenge := &HashcashHeader{Version: HeaderVersionBits, Difficulty: 1, Algorithm: "md5", Nonce: "nonce"}
+	if _, err := Solve(context.Background(), challenge, 1); err == nil {
+		t.Error("Solve() error = nil, want error")
+	}
+}
+
+func TestSolveStamp(t *testing.T) {
+	template, err := ParseHashcashStamp("1:12:251017120000:wisdom-gate::rand:")
+	if err != nil {
+		t.Fatalf("ParseHashcashStamp() error = %v", err)
+	}
+	if !IsStampTemplate(template.String()) {
+		t.Fatalf("IsStampTemplate(%q) = false, want true", template.String())
+	}
+
+	stamp, err := SolveStamp(context.Background(), template)
+	if err != nil {
+		t.Fatalf("SolveStamp() error = %v", err)
+	}
+
+	digest := sha1.Sum([]byte(stamp.String())) //nolint:gosec // SHA-1 задан спецификацией Hashcash v1
+	if !HasLeadingZeroBits(digest[:], 12) {
+		t.Errorf("SolveStamp() = %q does not have 12 zero bits", stamp.String())
+	}
+	if IsStampTemplate(stamp.String()) {
+		t.Errorf("IsStampTemplate(%q) = true for minted stamp", stamp.String())
+	}
+}
@@ -0,0 +1,93 @@
+package protocol
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Допустимые форматы даты марки по спецификации: YYMMDD[hhmm[ss]] в UTC
+var stampDateLayouts = []struct {
+	layout    string
+	precision time.Duration
+}{
+	{"060102", 24 * time.Hour},
+	{"0601021504", time.Minute},
+	{"060102150405", time.Second},
+}
+
+// HashcashStamp — марка Hashcash v1 (hashcash.org): ver:bits:date:resource:ext:rand:counter.
+// Поля хранятся в исходном виде, чтобы String() давал ту же строку, что пришла
+// от клиента, — хеш считается именно от неё.
+type HashcashStamp struct {
+	Version  int
+	Bits     int
+	Date     string
+	Resource string
+	Ext      string
+	Rand     string
+	Counter  string
+}
+
+func (s *HashcashStamp) String() string {
+	return fmt.Sprintf("%d:%d:%s:%s:%s:%s:%s",
+		s.Version, s.Bits, s.Date, s.Resource, s.Ext, s.Rand, s.Counter)
+}
+
+func ParseHashcashStamp(stamp string) (*HashcashStamp, error) {
+	parts := strings.Split(stamp, ":")
+	if len(parts) != 7 {
+		return nil, fmt.Errorf("invalid stamp format")
+	}
+
+	version, err := strconv.Atoi(parts[0])
+	if err != nil || version != HashcashStampVersion {
+		return nil, fmt.Errorf("unsupported stamp version: %s", parts[0])
+	}
+
+	bits, err := strconv.Atoi(parts[1])
+	if err != nil || bits < 0 {
+		return nil, fmt.Errorf("invalid stamp bits: %s", parts[1])
+	}
+
+	s := &HashcashStamp{
+		Version:  version,
+		Bits:     bits,
+		Date:     parts[2],
+		Resource: parts[3],
+		Ext:      parts[4],
+		Rand:     parts[5],
+		Counter:  parts[6],
+	}
+
+	if _, _, err := s.DatePeriod(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// DatePeriod возвращает интервал, который покрывает дата марки: у YYMMDD это
+// целые сутки, у YYMMDDhhmm — минута
+func (s *HashcashStamp) DatePeriod() (time.Time, time.Time, error) {
+	for _, f := range stampDateLayouts {
+		if len(s.Date) != len(f.layout) {
+			continue
+		}
+
+		start, err := time.ParseInLocation(f.layout, s.Date, time.UTC)
+		if err != nil {
+			break
+		}
+
+		return start, start.Add(f.precision), nil
+	}
+
+	return time.Time{}, time.Time{}, fmt.Errorf("invalid stamp date: %s", s.Date)
+}
+
+// FormatStampDate форматирует время с точностью до секунды
+func FormatStampDate(t time.Time) string {
+	return t.UTC().Format(stampDateLayouts[len(stampDateLayouts)-1].layout)
+}
@@ -0,0 +1,102 @@
+package protocol
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseHashcashStamp(t *testing.T) {
+	tests := []struct {
+		name    string
+		stamp   string
+		want    *HashcashStamp
+		wantErr bool
+	}{
+		{
+			name:  "date without time",
+			stamp: "1:20:060408:adam@cypherspace.org::1QTjaYd7niiQA/sc:ePa",
+			want: &HashcashStamp{
+				Version:  1,
+				Bits:     20,
+				Date:     "060408",
+				Resource: "adam@cypherspace.org",
+				Rand:     "1QTjaYd7niiQA/sc",
+				Counter:  "ePa",
+			},
+		},
+		{
+			name:  "date with minutes and extension",
+			stamp: "1:20:1303030600:wisdom-gate:ext=1:McMybZIhxKXu57jd:ckvi",
+			want: &HashcashStamp{
+				Version:  1,
+				Bits:     20,
+				Date:     "1303030600",
+				Resource: "wisdom-gate",
+				Ext:      "ext=1",
+				Rand:     "McMybZIhxKXu57jd",
+				Counter:  "ckvi",
+			},
+		},
+		{
+			name:  "template without counter",
+			stamp: "1:20:251017120000:wisdom-gate::rand:",
+			want: &HashcashStamp{
+				Version:  1,
+				Bits:     20,
+				Date:     "251017120000",
+				Resource: "wisdom-gate",
+				Rand:     "rand",
+			},
+		},
+		{name: "version 0", stamp: "0:20:060408:adam@cypherspace.org::1QTjaYd7niiQA/sc:ePa", wantErr: true},
+		{name: "too few fields", stamp: "1:20:060408:wisdom-gate:rand:counter", wantErr: true},
+		{name: "trailing field", stamp: "1:20:060408:wisdom-gate::rand:counter:extra", wantErr: true},
+		{name: "invalid bits", stamp: "1:-1:060408:wisdom-gate::rand:counter", wantErr: true},
+		{name: "invalid date", stamp: "1:20:0604:wisdom-gate::rand:counter", wantErr: true},
+		{name: "impossible date", stamp: "1:20:061341:wisdom-gate::rand:counter", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseHashcashStamp(tt.stamp)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseHashcashStamp() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if *got != *tt.want {
+				t.Errorf("ParseHashcashStamp() = %+v, want %+v", *got, *tt.want)
+			}
+			if got.String() != tt.stamp {
+				t.Errorf("String() = %v, want %v", got.String(), tt.stamp)
+			}
+		})
+	}
+}
+
+func TestHashcashStamp_DatePeriod(t *testing.T) {
+	tests := []struct {
+		date      string
+		wantStart time.Time
+		wantEnd   time.Time
+	}{
+		{"060408", time.Date(2006, 4, 8, 0, 0, 0, 0, time.UTC), time.Date(2006, 4, 9, 0, 0, 0, 0, time.UTC)},
+		{"0604081230", time.Date(2006, 4, 8, 12, 30, 0, 0, time.UTC), time.Date(2006, 4, 8, 12, 31, 0, 0, time.UTC)},
+		{"060408123015", time.Date(2006, 4, 8, 12, 30, 15, 0, time.UTC), time.Date(2006, 4, 8, 12, 30, 16, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.date, func(t *testing.T) {
+			stamp := &HashcashStamp{Date: tt.date}
+			start, end, err := stamp.DatePeriod()
+			if err != nil {
+				t.Fatalf("DatePeriod() error = %v", err)
+			}
+			if !start.Equal(tt.wantStart) || !end.Equal(tt.wantEnd) {
+				t.Errorf("DatePeriod() = %v, %v, want %v, %v", start, end, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}